@@ -1,5 +1,10 @@
-module MineMock
+module github.com/ketchandmayo/MineMock
 
 go 1.25
 
+require (
+	github.com/BurntSushi/toml v1.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require github.com/Tnze/go-mc v1.20.2 // indirect
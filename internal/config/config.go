@@ -1,58 +1,170 @@
 package config
 
 import (
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"math"
 	"net"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+	"github.com/ketchandmayo/MineMock/internal/session"
+)
+
+const (
+	envIP                           = "IP"
+	envPort                         = "PORT"
+	envError                        = "ERROR"
+	envErrorDelaySeconds            = "ERROR_DELAY_SECONDS"
+	envForceConnectionLostTitle     = "FORCE_CONNECTION_LOST_TITLE"
+	envMOTD                         = "MOTD"
+	envVersionName                  = "VERSION_NAME"
+	envProtocol                     = "PROTOCOL"
+	envMaxPlayers                   = "MAX_PLAYERS"
+	envOnlinePlayers                = "ONLINE_PLAYERS"
+	envFavicon                      = "FAVICON"
+	envPlayerSample                 = "PLAYER_SAMPLE"
+	envEnforcesSecureChat           = "ENFORCES_SECURE_CHAT"
+	envPreviewsChat                 = "PREVIEWS_CHAT"
+	envRealServerAddr               = "REAL_SERVER_ADDR"
+	envLoginWhitelist               = "LOGIN_WHITELIST"
+	envUseClientUUID                = "USE_CLIENT_UUID"
+	envCompressionThreshold         = "COMPRESSION_THRESHOLD"
+	envOnlineMode                   = "ONLINE_MODE"
+	envSessionServerURL             = "SESSION_SERVER_URL"
+	envMockSessionServerAddr        = "MOCK_SESSION_SERVER_ADDR"
+	envMockSessionAccounts          = "MOCK_SESSION_ACCOUNTS"
+	envLoginPluginRequests          = "LOGIN_PLUGIN_REQUESTS"
+	envLoginPluginRequireUnderstood = "LOGIN_PLUGIN_REQUIRE_UNDERSTOOD"
+	envTransferRules                = "TRANSFER_RULES"
+	envCookieRequests               = "COOKIE_REQUESTS"
+	envStoreCookies                 = "STORE_COOKIES"
+	envLimbo                        = "LIMBO"
+	envLimboTitle                   = "LIMBO_TITLE"
+	envLimboSubtitle                = "LIMBO_SUBTITLE"
+	envLimboChat                    = "LIMBO_CHAT"
+	envLimboActionBar               = "LIMBO_ACTIONBAR"
+	envQueue                        = "QUEUE"
+	envQueueThroughput              = "QUEUE_THROUGHPUT"
+	envQueueRelease                 = "QUEUE_RELEASE"
+	envQueueDisplay                 = "QUEUE_DISPLAY"
+	envQueueMessage                 = "QUEUE_MESSAGE"
+	envQueueReleaseMessage          = "QUEUE_RELEASE_MESSAGE"
+	envKeepAliveIntervalSeconds     = "KEEP_ALIVE_INTERVAL_SECONDS"
+	envKeepAliveTimeoutSeconds      = "KEEP_ALIVE_TIMEOUT_SECONDS"
+	envPlayHoldSeconds              = "PLAY_HOLD_SECONDS"
+	envHandshakeTimeoutSeconds      = "HANDSHAKE_TIMEOUT_SECONDS"
+	envStatusTimeoutSeconds         = "STATUS_TIMEOUT_SECONDS"
+	envLoginTimeoutSeconds          = "LOGIN_TIMEOUT_SECONDS"
+	envIdleTimeoutSeconds           = "IDLE_TIMEOUT_SECONDS"
+	envShutdownMessage              = "SHUTDOWN_MESSAGE"
+	envDrainTimeoutSeconds          = "DRAIN_TIMEOUT_SECONDS"
+	envAmpersandColorCodes          = "AMPERSAND_COLOR_CODES"
+	envSimpleVoicechatPort          = "SIMPLE_VOICECHAT_PORT"
+)
+
+const (
+	defaultIP                             = "127.0.0.1"
+	defaultPort                           = "25565"
+	defaultVersionName                    = "1.20.1"
+	defaultProtocol                 int32 = 763
+	defaultMaxPlayers                     = 20
+	defaultOnlinePlayers                  = 7
+	defaultSimpleVoicechatPort            = 24454
+	defaultCompressionThreshold           = -1
+	defaultQueueThroughput                = 6
+	defaultKeepAliveIntervalSeconds       = 10
+	defaultKeepAliveTimeoutSeconds        = 15
+	defaultHandshakeTimeoutSeconds        = 5
+	defaultStatusTimeoutSeconds           = 10
+	defaultLoginTimeoutSeconds            = 30
+	defaultIdleTimeoutSeconds             = 30
+	defaultDrainTimeoutSeconds            = 30
 )
 
 const (
-	envIP                       = "IP"
-	envPort                     = "PORT"
-	envError                    = "ERROR"
-	envErrorDelaySeconds        = "ERROR_DELAY_SECONDS"
-	envForceConnectionLostTitle = "FORCE_CONNECTION_LOST_TITLE"
-	envMOTD                     = "MOTD"
-	envVersionName              = "VERSION_NAME"
-	envProtocol                 = "PROTOCOL"
-	envMaxPlayers               = "MAX_PLAYERS"
-	envOnlinePlayers            = "ONLINE_PLAYERS"
-	envRealServerAddr           = "REAL_SERVER_ADDR"
-	envLoginWhitelist           = "LOGIN_WHITELIST"
-	envSimpleVoicechatPort      = "SIMPLE_VOICECHAT_PORT"
+	queueReleaseTransfer  = "transfer"
+	queueReleaseProxy     = "proxy"
+	queueDisplayActionBar = "actionbar"
+	queueDisplayBossBar   = "bossbar"
+	queueDisplayBoth      = "both"
 )
 
 const (
-	defaultIP                        = "127.0.0.1"
-	defaultPort                      = "25565"
-	defaultVersionName               = "1.20.1"
-	defaultProtocol            int32 = 763
-	defaultMaxPlayers                = 20
-	defaultOnlinePlayers             = 7
-	defaultSimpleVoicechatPort       = 24454
+	defaultMinecraftPort   = "25565"
+	transferRuleHostPrefix = "host:"
+	transferRuleFallback   = "*"
 )
 
 const (
-	defaultErrorMessage = "\\u00a7c\\u00a7oMine\\u00a74\\u00a7oMock\\u00a7r\\n\\u00a72Server is working"
-	defaultMOTD         = "\u00a7c\u00a7oMine\u00a74\u00a7oMock\u00a7r\\n\u00a76Minecraft mock server on golang\u00a7r | \u00a7eWelcome\u263a"
+	defaultErrorMessage        = "\\u00a7c\\u00a7oMine\\u00a74\\u00a7oMock\\u00a7r\\n\\u00a72Server is working"
+	defaultMOTD                = "\u00a7c\u00a7oMine\u00a74\u00a7oMock\u00a7r\\n\u00a76Minecraft mock server on golang\u00a7r | \u00a7eWelcome\u263a"
+	defaultLimboTitle          = "\u00a76Maintenance"
+	defaultLimboSubtitle       = "\u00a77Please wait, the server will be back soon"
+	defaultQueueMessage        = "\u00a7ePosition in queue: \u00a76{position}"
+	defaultQueueReleaseMessage = "\u00a7aIt's your turn! Reconnect to join the server."
 )
 
 type Config struct {
-	IP                       string
-	Port                     string
-	ErrorMessage             string
-	ErrorDelay               time.Duration
-	ForceConnectionLostTitle bool
-	MOTD                     string
-	VersionName              string
-	Protocol                 int32
-	MaxPlayers               int32
-	OnlinePlayers            int32
-	RealServerAddr           string
-	LoginWhitelist           map[string]struct{}
-	SimpleVoicechatPort      int
+	IP                           string
+	Port                         string
+	ErrorMessage                 string
+	ErrorDelay                   time.Duration
+	ForceConnectionLostTitle     bool
+	MOTD                         string
+	VersionName                  string
+	Protocol                     int32
+	MaxPlayers                   int32
+	OnlinePlayers                int32
+	FaviconPath                  string
+	PlayerSample                 []protocol.StatusPlayerSample
+	EnforcesSecureChat           bool
+	PreviewsChat                 bool
+	RealServerAddr               string
+	LoginWhitelist               map[string]struct{}
+	UseClientUUID                bool
+	CompressionThreshold         int32
+	OnlineMode                   bool
+	SessionServerURL             string
+	MockSessionServerAddr        string
+	MockSessionAccounts          []session.Account
+	LoginPluginRequests          []protocol.LoginPluginRequest
+	LoginPluginRequireUnderstood bool
+	TransferRules                map[string]string
+	CookieRequests               []string
+	StoreCookies                 []protocol.Cookie
+	Limbo                        bool
+	LimboTitle                   string
+	LimboSubtitle                string
+	LimboChat                    string
+	LimboActionBar               string
+	Queue                        bool
+	QueueThroughput              int
+	QueueRelease                 string
+	QueueActionBar               bool
+	QueueBossBar                 bool
+	QueueMessage                 string
+	QueueReleaseMessage          string
+	KeepAliveInterval            time.Duration
+	KeepAliveTimeout             time.Duration
+	PlayHoldTime                 time.Duration
+	HandshakeTimeout             time.Duration
+	StatusTimeout                time.Duration
+	LoginTimeout                 time.Duration
+	IdleTimeout                  time.Duration
+	ShutdownMessage              string
+	DrainTimeout                 time.Duration
+	AmpersandColorCodes          bool
+	SimpleVoicechatPort          int
+
+	// problems are the values ignored while reading the configuration,
+	// reported by Validate.
+	problems []string
 }
 
 var versionProtocolMap = map[string]int32{
@@ -71,43 +183,114 @@ var versionProtocolMap = map[string]int32{
 	"1.21.4": 769,
 }
 
+// FromEnv reads the configuration from the environment variables.
 func FromEnv() Config {
-	versionName := stringFromEnv(envVersionName, defaultVersionName)
+	return source{lookupEnv: os.LookupEnv}.config()
+}
+
+// Defaults returns the configuration used when nothing is set, ignoring the
+// environment.
+func Defaults() Config {
+	return source{}.config()
+}
+
+func (s source) config() Config {
+	s.problems = new([]string)
+	for _, key := range slices.Sorted(maps.Keys(s.unknownFileKeys)) {
+		s.invalid(key, s.unknownFileKeys[key], "a known setting")
+	}
+	versionName := s.stringFromEnv(envVersionName, defaultVersionName)
+	ampersandColorCodes := s.boolFromEnv(envAmpersandColorCodes, false)
+	mockSessionServerAddr := strings.TrimSpace(s.stringFromEnv(envMockSessionServerAddr, ""))
+	queueActionBar, queueBossBar := s.queueDisplayFromEnv(envQueueDisplay)
 
 	return Config{
-		IP:                       stringFromEnv(envIP, defaultIP),
-		Port:                     stringFromEnv(envPort, defaultPort),
-		ErrorMessage:             decodedStringFromEnv(envError, defaultErrorMessage),
-		ErrorDelay:               secondsDurationFromEnv(envErrorDelaySeconds, 0),
-		ForceConnectionLostTitle: boolFromEnv(envForceConnectionLostTitle, false),
-		MOTD:                     decodedStringFromEnv(envMOTD, defaultMOTD),
-		VersionName:              versionName,
-		Protocol:                 protocolFromEnv(versionName),
-		MaxPlayers:               int32FromEnv(envMaxPlayers, defaultMaxPlayers),
-		OnlinePlayers:            int32FromEnv(envOnlinePlayers, defaultOnlinePlayers),
-		RealServerAddr:           stringFromEnv(envRealServerAddr, ""),
-		LoginWhitelist:           usernameSetFromEnv(envLoginWhitelist),
-		SimpleVoicechatPort:      portFromEnv(envSimpleVoicechatPort, defaultSimpleVoicechatPort),
-	}
-}
-
-func secondsDurationFromEnv(key string, fallbackSeconds int64) time.Duration {
-	parsed, ok := int64FromEnv(key)
-	if !ok || parsed < 0 {
+		IP:                           s.stringFromEnv(envIP, defaultIP),
+		Port:                         s.stringFromEnv(envPort, defaultPort),
+		ErrorMessage:                 s.formattedStringFromEnv(envError, defaultErrorMessage, ampersandColorCodes),
+		ErrorDelay:                   s.secondsDurationFromEnv(envErrorDelaySeconds, 0),
+		ForceConnectionLostTitle:     s.boolFromEnv(envForceConnectionLostTitle, false),
+		MOTD:                         s.formattedStringFromEnv(envMOTD, defaultMOTD, ampersandColorCodes),
+		VersionName:                  versionName,
+		Protocol:                     s.protocolFromEnv(versionName),
+		MaxPlayers:                   s.int32FromEnv(envMaxPlayers, defaultMaxPlayers),
+		OnlinePlayers:                s.int32FromEnv(envOnlinePlayers, defaultOnlinePlayers),
+		FaviconPath:                  s.stringFromEnv(envFavicon, ""),
+		PlayerSample:                 s.playerSampleFromEnv(envPlayerSample),
+		EnforcesSecureChat:           s.boolFromEnv(envEnforcesSecureChat, false),
+		PreviewsChat:                 s.boolFromEnv(envPreviewsChat, false),
+		RealServerAddr:               s.stringFromEnv(envRealServerAddr, ""),
+		LoginWhitelist:               s.usernameSetFromEnv(envLoginWhitelist),
+		UseClientUUID:                s.boolFromEnv(envUseClientUUID, false),
+		CompressionThreshold:         s.int32FromEnv(envCompressionThreshold, defaultCompressionThreshold),
+		OnlineMode:                   s.boolFromEnv(envOnlineMode, false),
+		SessionServerURL:             s.sessionServerURLFromEnv(mockSessionServerAddr),
+		MockSessionServerAddr:        mockSessionServerAddr,
+		MockSessionAccounts:          s.sessionAccountsFromEnv(envMockSessionAccounts),
+		LoginPluginRequests:          s.loginPluginRequestsFromEnv(envLoginPluginRequests),
+		LoginPluginRequireUnderstood: s.boolFromEnv(envLoginPluginRequireUnderstood, false),
+		TransferRules:                s.transferRulesFromEnv(envTransferRules),
+		CookieRequests:               s.listFromEnv(envCookieRequests),
+		StoreCookies:                 s.cookiesFromEnv(envStoreCookies),
+		Limbo:                        s.boolFromEnv(envLimbo, false),
+		LimboTitle:                   s.formattedStringFromEnv(envLimboTitle, defaultLimboTitle, ampersandColorCodes),
+		LimboSubtitle:                s.formattedStringFromEnv(envLimboSubtitle, defaultLimboSubtitle, ampersandColorCodes),
+		LimboChat:                    s.formattedStringFromEnv(envLimboChat, "", ampersandColorCodes),
+		LimboActionBar:               s.formattedStringFromEnv(envLimboActionBar, "", ampersandColorCodes),
+		Queue:                        s.boolFromEnv(envQueue, false),
+		QueueThroughput:              s.positiveIntFromEnv(envQueueThroughput, defaultQueueThroughput),
+		QueueRelease:                 s.queueReleaseFromEnv(envQueueRelease),
+		QueueActionBar:               queueActionBar,
+		QueueBossBar:                 queueBossBar,
+		QueueMessage:                 s.formattedStringFromEnv(envQueueMessage, defaultQueueMessage, ampersandColorCodes),
+		QueueReleaseMessage:          s.formattedStringFromEnv(envQueueReleaseMessage, defaultQueueReleaseMessage, ampersandColorCodes),
+		KeepAliveInterval:            time.Duration(s.positiveIntFromEnv(envKeepAliveIntervalSeconds, defaultKeepAliveIntervalSeconds)) * time.Second,
+		KeepAliveTimeout:             time.Duration(s.positiveIntFromEnv(envKeepAliveTimeoutSeconds, defaultKeepAliveTimeoutSeconds)) * time.Second,
+		PlayHoldTime:                 s.secondsDurationFromEnv(envPlayHoldSeconds, 0),
+		HandshakeTimeout:             s.secondsDurationFromEnv(envHandshakeTimeoutSeconds, defaultHandshakeTimeoutSeconds),
+		StatusTimeout:                s.secondsDurationFromEnv(envStatusTimeoutSeconds, defaultStatusTimeoutSeconds),
+		LoginTimeout:                 s.secondsDurationFromEnv(envLoginTimeoutSeconds, defaultLoginTimeoutSeconds),
+		IdleTimeout:                  s.secondsDurationFromEnv(envIdleTimeoutSeconds, defaultIdleTimeoutSeconds),
+		ShutdownMessage:              s.formattedStringFromEnv(envShutdownMessage, "", ampersandColorCodes),
+		DrainTimeout:                 s.secondsDurationFromEnv(envDrainTimeoutSeconds, defaultDrainTimeoutSeconds),
+		AmpersandColorCodes:          ampersandColorCodes,
+		SimpleVoicechatPort:          s.portFromEnv(envSimpleVoicechatPort, defaultSimpleVoicechatPort),
+		problems:                     *s.problems,
+	}
+}
+
+func (s source) secondsDurationFromEnv(key string, fallbackSeconds int64) time.Duration {
+	parsed, ok := s.int64FromEnv(key)
+	if !ok {
+		return time.Duration(fallbackSeconds) * time.Second
+	}
+	if parsed < 0 {
+		s.invalid(key, strconv.FormatInt(parsed, 10), "0 or more seconds")
 		return time.Duration(fallbackSeconds) * time.Second
 	}
 
 	return time.Duration(parsed) * time.Second
 }
 
-func decodedStringFromEnv(key string, fallback string) string {
-	if value, ok := lookupNonEmptyEnv(key); ok {
+func (s source) decodedStringFromEnv(key string, fallback string) string {
+	if value, ok := s.lookupNonEmptyEnv(key); ok {
 		return decodeServerPropertiesEscapes(value)
 	}
 
 	return decodeServerPropertiesEscapes(fallback)
 }
 
+// formattedStringFromEnv decodes a chat message and, when ampersandColorCodes is
+// set, translates "&c"-style codes to the § form understood by the client.
+func (s source) formattedStringFromEnv(key string, fallback string, ampersandColorCodes bool) string {
+	value := s.decodedStringFromEnv(key, fallback)
+	if !ampersandColorCodes {
+		return value
+	}
+
+	return protocol.TranslateAlternateColorCodes('&', value)
+}
+
 func decodeServerPropertiesEscapes(input string) string {
 	decoded, err := strconv.Unquote(`"` + strings.ReplaceAll(input, `"`, `\\"`) + `"`)
 	if err != nil {
@@ -117,96 +300,162 @@ func decodeServerPropertiesEscapes(input string) string {
 	return decoded
 }
 
-func stringFromEnv(key string, fallback string) string {
-	if value, ok := lookupNonEmptyEnv(key); ok {
+func (s source) stringFromEnv(key string, fallback string) string {
+	if value, ok := s.lookupNonEmptyEnv(key); ok {
 		return value
 	}
 
 	return fallback
 }
 
-func int32FromEnv(key string, fallback int32) int32 {
-	parsed, ok := int64FromEnv(key)
+func (s source) int32FromEnv(key string, fallback int32) int32 {
+	parsed, ok := s.int32FromEnvValue(key)
 	if !ok {
 		return fallback
 	}
 
-	return int32(parsed)
+	return parsed
 }
 
-func int64FromEnv(key string) (int64, bool) {
-	value, ok := lookupNonEmptyEnv(key)
+func (s source) int64FromEnv(key string) (int64, bool) {
+	value, ok := s.lookupNonEmptyEnv(key)
 	if !ok {
 		return 0, false
 	}
 
 	parsed, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
 	if err != nil {
+		s.invalid(key, value, "an integer")
 		return 0, false
 	}
 
 	return parsed, true
 }
 
-func boolFromEnv(key string, fallback bool) bool {
-	value, ok := lookupNonEmptyEnv(key)
+func (s source) boolFromEnv(key string, fallback bool) bool {
+	value, ok := s.lookupNonEmptyEnv(key)
 	if !ok {
 		return fallback
 	}
 
 	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
 	if err != nil {
+		s.invalid(key, value, "true or false")
 		return fallback
 	}
 
 	return parsed
 }
 
-func protocolFromEnv(versionName string) int32 {
-	if parsed, ok := int32FromEnvValue(envProtocol); ok {
+func (s source) protocolFromEnv(versionName string) int32 {
+	if parsed, ok := s.int32FromEnvValue(envProtocol); ok {
 		return parsed
 	}
 
 	if protocol, ok := versionProtocolMap[strings.TrimSpace(versionName)]; ok {
 		return protocol
 	}
+	if _, ok := s.lookupNonEmptyEnv(envProtocol); !ok {
+		s.invalid(envVersionName, versionName, "a known version, or "+envProtocol+" set to its protocol number")
+	}
 
 	return defaultProtocol
 }
 
-func int32FromEnvValue(key string) (int32, bool) {
-	value, ok := int64FromEnv(key)
+func (s source) int32FromEnvValue(key string) (int32, bool) {
+	value, ok := s.int64FromEnv(key)
 	if !ok {
 		return 0, false
 	}
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		s.invalid(key, strconv.FormatInt(value, 10), "an integer that fits in 32 bits")
+		return 0, false
+	}
 
 	return int32(value), true
 }
 
-func portFromEnv(key string, fallback int) int {
-	parsed, ok := int64FromEnv(key)
-	if !ok || parsed < 1 || parsed > 65535 {
+func (s source) portFromEnv(key string, fallback int) int {
+	parsed, ok := s.int64FromEnv(key)
+	if !ok {
+		return fallback
+	}
+	if parsed < 1 || parsed > 65535 {
+		s.invalid(key, strconv.FormatInt(parsed, 10), "a port between 1 and 65535")
 		return fallback
 	}
 
 	return int(parsed)
 }
 
-func lookupNonEmptyEnv(key string) (string, bool) {
-	value, ok := os.LookupEnv(key)
+func (s source) positiveIntFromEnv(key string, fallback int) int {
+	parsed, ok := s.int64FromEnv(key)
 	if !ok {
-		return "", false
+		return fallback
+	}
+	if parsed < 1 {
+		s.invalid(key, strconv.FormatInt(parsed, 10), "a positive integer")
+		return fallback
 	}
 
-	if strings.TrimSpace(value) == "" {
-		return "", false
+	return int(parsed)
+}
+
+func (s source) queueReleaseFromEnv(key string) string {
+	value := strings.ToLower(strings.TrimSpace(s.stringFromEnv(key, "")))
+	switch value {
+	case queueReleaseProxy:
+		return queueReleaseProxy
+	case "", queueReleaseTransfer:
+	default:
+		s.invalid(key, value, queueReleaseTransfer+" or "+queueReleaseProxy)
 	}
 
-	return value, true
+	return queueReleaseTransfer
 }
 
-func usernameSetFromEnv(key string) map[string]struct{} {
-	value, ok := lookupNonEmptyEnv(key)
+// queueDisplayFromEnv returns whether the queue position is shown in the
+// action bar and in a boss bar; anything but "bossbar" and "both" means the
+// action bar only.
+func (s source) queueDisplayFromEnv(key string) (bool, bool) {
+	switch value := strings.ToLower(strings.TrimSpace(s.stringFromEnv(key, ""))); value {
+	case queueDisplayBossBar:
+		return false, true
+	case queueDisplayBoth:
+		return true, true
+	case "", queueDisplayActionBar:
+		return true, false
+	default:
+		s.invalid(key, value, queueDisplayActionBar+", "+queueDisplayBossBar+" or "+queueDisplayBoth)
+		return true, false
+	}
+}
+
+// invalid records that value of key was ignored because it is not want.
+func (s source) invalid(key string, value string, want string) {
+	*s.problems = append(*s.problems, fmt.Sprintf("%s=%q: want %s", key, strings.TrimSpace(value), want))
+}
+
+// lookupNonEmptyEnv returns the value of the environment variable key or,
+// when it is unset or blank, the value of key in the config file.
+func (s source) lookupNonEmptyEnv(key string) (string, bool) {
+	if s.lookupEnv != nil {
+		if value, ok := s.lookupEnv(key); ok && strings.TrimSpace(value) != "" {
+			return value, true
+		}
+	}
+
+	if value, ok := s.file[key]; ok && strings.TrimSpace(value) != "" {
+		return value, true
+	}
+
+	return "", false
+}
+
+// usernameSetFromEnv parses a list of usernames and player UUIDs. Usernames are
+// case-folded; UUIDs (dashed or not) are stored in their canonical dashed form.
+func (s source) usernameSetFromEnv(key string) map[string]struct{} {
+	value, ok := s.lookupNonEmptyEnv(key)
 	if !ok {
 		return map[string]struct{}{}
 	}
@@ -217,23 +466,292 @@ func usernameSetFromEnv(key string) map[string]struct{} {
 	})
 
 	for _, part := range parts {
-		username := strings.ToLower(strings.TrimSpace(part))
-		if username == "" {
+		entry := strings.TrimSpace(part)
+		if entry == "" {
 			continue
 		}
-		set[username] = struct{}{}
+		if uuid, err := protocol.ParseUUID(entry); err == nil {
+			set[uuid.String()] = struct{}{}
+			continue
+		}
+		set[strings.ToLower(entry)] = struct{}{}
 	}
 
 	return set
 }
 
-func (c Config) IsLoginWhitelisted(username string) bool {
+// playerSampleFromEnv parses "Name" or "Name:UUID" entries for players.sample.
+// Entries without a UUID get the offline-mode UUID of the name; entries with an
+// invalid UUID are skipped.
+func (s source) playerSampleFromEnv(key string) []protocol.StatusPlayerSample {
+	value, ok := s.lookupNonEmptyEnv(key)
+	if !ok {
+		return nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+
+	sample := make([]protocol.StatusPlayerSample, 0, len(parts))
+	for _, part := range parts {
+		name, rawUUID, _ := strings.Cut(strings.TrimSpace(part), ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		uuid := protocol.OfflinePlayerUUID(name)
+		if strings.TrimSpace(rawUUID) != "" {
+			parsed, err := protocol.ParseUUID(rawUUID)
+			if err != nil {
+				s.invalid(key, part, "Name or Name:UUID entries")
+				continue
+			}
+			uuid = parsed
+		}
+
+		sample = append(sample, protocol.StatusPlayerSample{Name: name, ID: uuid.String()})
+	}
+
+	return sample
+}
+
+// sessionServerURLFromEnv defaults to the built-in mock session server when it
+// is enabled, so online mode works offline without further configuration.
+func (s source) sessionServerURLFromEnv(mockSessionServerAddr string) string {
+	fallback := session.DefaultURL
+	if mockSessionServerAddr != "" {
+		fallback = "http://" + mockSessionServerAddr
+	}
+
+	return strings.TrimSpace(s.stringFromEnv(envSessionServerURL, fallback))
+}
+
+// sessionAccountsFromEnv parses "Name", "Name:UUID" or "Name:UUID:SkinURL"
+// entries for the mock session server. Entries without a UUID get the
+// offline-mode UUID of the name; entries with an invalid UUID are skipped.
+func (s source) sessionAccountsFromEnv(key string) []session.Account {
+	value, ok := s.lookupNonEmptyEnv(key)
+	if !ok {
+		return nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+
+	accounts := make([]session.Account, 0, len(parts))
+	for _, part := range parts {
+		fields := strings.SplitN(strings.TrimSpace(part), ":", 3)
+		name := strings.TrimSpace(fields[0])
+		if name == "" {
+			continue
+		}
+
+		account := session.Account{Name: name, UUID: protocol.OfflinePlayerUUID(name)}
+		if len(fields) > 1 && strings.TrimSpace(fields[1]) != "" {
+			parsed, err := protocol.ParseUUID(fields[1])
+			if err != nil {
+				s.invalid(key, part, "Name, Name:UUID or Name:UUID:SkinURL entries")
+				continue
+			}
+			account.UUID = parsed
+		}
+		if len(fields) > 2 {
+			account.SkinURL = strings.TrimSpace(fields[2])
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts
+}
+
+// loginPluginRequestsFromEnv parses "channel" or "channel=hexpayload" entries.
+// Message ids follow the order of the entries; entries with a payload that is
+// not valid hex are skipped.
+func (s source) loginPluginRequestsFromEnv(key string) []protocol.LoginPluginRequest {
+	value, ok := s.lookupNonEmptyEnv(key)
+	if !ok {
+		return nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+
+	requests := make([]protocol.LoginPluginRequest, 0, len(parts))
+	for _, part := range parts {
+		channel, rawPayload, _ := strings.Cut(strings.TrimSpace(part), "=")
+		channel = strings.TrimSpace(channel)
+		if channel == "" {
+			continue
+		}
+
+		payload, err := hex.DecodeString(strings.TrimSpace(rawPayload))
+		if err != nil {
+			s.invalid(key, part, "channel or channel=hexpayload entries")
+			continue
+		}
+
+		requests = append(requests, protocol.LoginPluginRequest{
+			MessageID: int32(len(requests)),
+			Channel:   channel,
+			Data:      payload,
+		})
+	}
+
+	return requests
+}
+
+// transferRulesFromEnv parses "match=host[:port]" entries. match is a
+// username, a player UUID, "host:<address>" for the address the client
+// connected to, or "*" for everyone else. Entries with an invalid target are
+// skipped.
+func (s source) transferRulesFromEnv(key string) map[string]string {
+	value, ok := s.lookupNonEmptyEnv(key)
+	if !ok {
+		return map[string]string{}
+	}
+
+	rules := map[string]string{}
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+
+	for _, part := range parts {
+		match, target, found := strings.Cut(strings.TrimSpace(part), "=")
+		match = strings.TrimSpace(match)
+		if !found || match == "" {
+			s.invalid(key, part, "match=host[:port] entries")
+			continue
+		}
+
+		target, ok := transferTarget(strings.TrimSpace(target))
+		if !ok {
+			s.invalid(key, part, "match=host[:port] entries")
+			continue
+		}
+
+		rules[transferRuleKey(match)] = target
+	}
+
+	return rules
+}
+
+func transferRuleKey(match string) string {
+	if uuid, err := protocol.ParseUUID(match); err == nil {
+		return uuid.String()
+	}
+
+	return strings.ToLower(match)
+}
+
+// transferTarget validates a "host[:port]" target, adding the default port.
+func transferTarget(target string) (string, bool) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		host, port = target, defaultMinecraftPort
+	}
+	if host == "" {
+		return "", false
+	}
+
+	parsedPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || parsedPort == 0 {
+		return "", false
+	}
+
+	return net.JoinHostPort(host, port), true
+}
+
+// TransferTarget returns the transfer target for a player, preferring rules for
+// the UUID, then the username, then the server address and finally "*".
+func (c Config) TransferTarget(username string, playerUUID string, serverHost string) (string, bool) {
+	if len(c.TransferRules) == 0 {
+		return "", false
+	}
+
+	keys := make([]string, 0, 4)
+	if uuid, err := protocol.ParseUUID(playerUUID); err == nil {
+		keys = append(keys, uuid.String())
+	}
+	keys = append(keys, strings.ToLower(strings.TrimSpace(username)))
+	if serverHost != "" {
+		keys = append(keys, transferRuleHostPrefix+strings.ToLower(serverHost))
+	}
+	keys = append(keys, transferRuleFallback)
+
+	for _, key := range keys {
+		if target, ok := c.TransferRules[key]; ok {
+			return target, true
+		}
+	}
+
+	return "", false
+}
+
+func (s source) listFromEnv(key string) []string {
+	value, ok := s.lookupNonEmptyEnv(key)
+	if !ok {
+		return nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';'
+	})
+
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if entry := strings.TrimSpace(part); entry != "" {
+			list = append(list, entry)
+		}
+	}
+
+	return list
+}
+
+// cookiesFromEnv parses "key=value" entries; the value is stored as text.
+// Entries without a key are skipped.
+func (s source) cookiesFromEnv(key string) []protocol.Cookie {
+	entries := s.listFromEnv(key)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	cookies := make([]protocol.Cookie, 0, len(entries))
+	for _, entry := range entries {
+		cookieKey, value, _ := strings.Cut(entry, "=")
+		cookieKey = strings.TrimSpace(cookieKey)
+		if cookieKey == "" {
+			s.invalid(key, entry, "key=value entries")
+			continue
+		}
+
+		cookies = append(cookies, protocol.Cookie{Key: cookieKey, Payload: []byte(value)})
+	}
+
+	return cookies
+}
+
+// IsLoginWhitelisted matches the username case-insensitively, or the player UUID
+// reported by the client in Login Start when it is not empty.
+func (c Config) IsLoginWhitelisted(username string, playerUUID string) bool {
 	if len(c.LoginWhitelist) == 0 {
 		return false
 	}
 
-	_, ok := c.LoginWhitelist[strings.ToLower(strings.TrimSpace(username))]
-	return ok
+	if _, ok := c.LoginWhitelist[strings.ToLower(strings.TrimSpace(username))]; ok {
+		return true
+	}
+
+	if uuid, err := protocol.ParseUUID(playerUUID); err == nil {
+		_, ok := c.LoginWhitelist[uuid.String()]
+		return ok
+	}
+
+	return false
 }
 
 func (c Config) Address() string {
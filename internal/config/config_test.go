@@ -1,9 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
 )
 
 func TestProtocolFromEnv_UsesVersionMapWhenProtocolMissing(t *testing.T) {
@@ -121,17 +127,36 @@ func TestFromEnv_LoginWhitelistParsing(t *testing.T) {
 	if len(cfg.LoginWhitelist) != 3 {
 		t.Fatalf("expected 3 whitelist users, got %d", len(cfg.LoginWhitelist))
 	}
-	if !cfg.IsLoginWhitelisted("steve") {
+	if !cfg.IsLoginWhitelisted("steve", "") {
 		t.Fatal("expected steve to be whitelisted")
 	}
-	if !cfg.IsLoginWhitelisted("ALEX") {
+	if !cfg.IsLoginWhitelisted("ALEX", "") {
 		t.Fatal("expected ALEX to be whitelisted (case-insensitive)")
 	}
-	if cfg.IsLoginWhitelisted("Herobrine") {
+	if cfg.IsLoginWhitelisted("Herobrine", "") {
 		t.Fatal("expected Herobrine not to be whitelisted")
 	}
 }
 
+func TestFromEnv_LoginWhitelistUUIDs(t *testing.T) {
+	t.Setenv("LOGIN_WHITELIST", "Steve, 069A79F444E94726A5BEFCA90E38AAF5")
+
+	cfg := FromEnv()
+
+	if !cfg.IsLoginWhitelisted("Notch", "069a79f4-44e9-4726-a5be-fca90e38aaf5") {
+		t.Fatal("expected client-reported uuid to be whitelisted")
+	}
+	if cfg.IsLoginWhitelisted("Notch", "") {
+		t.Fatal("expected Notch without uuid not to be whitelisted")
+	}
+	if cfg.IsLoginWhitelisted("Herobrine", "f84c6a79-0a4e-45e0-879b-cd49ebd4c4e2") {
+		t.Fatal("expected unknown uuid not to be whitelisted")
+	}
+	if !cfg.IsLoginWhitelisted("steve", "f84c6a79-0a4e-45e0-879b-cd49ebd4c4e2") {
+		t.Fatal("expected username match to win regardless of uuid")
+	}
+}
+
 func TestFromEnv_RealServerAddr(t *testing.T) {
 	t.Setenv("REAL_SERVER_ADDR", "play.example.com:25565")
 
@@ -179,3 +204,485 @@ func TestConfig_RealServerVoicechatAddress(t *testing.T) {
 		t.Fatalf("unexpected voicechat backend address: %q", got)
 	}
 }
+
+func TestVersionProtocolMap_HasPacketTables(t *testing.T) {
+	for versionName, protocolVersion := range versionProtocolMap {
+		if _, ok := protocol.LookupVersion(protocolVersion); !ok {
+			t.Fatalf("no packet table for %s (protocol %d)", versionName, protocolVersion)
+		}
+	}
+}
+
+func TestFromEnv_UseClientUUID(t *testing.T) {
+	t.Setenv("USE_CLIENT_UUID", "true")
+
+	cfg := FromEnv()
+	if !cfg.UseClientUUID {
+		t.Fatal("expected UseClientUUID to be true")
+	}
+}
+
+func TestFromEnv_CompressionThreshold(t *testing.T) {
+	if cfg := FromEnv(); cfg.CompressionThreshold != -1 {
+		t.Fatalf("expected compression to be disabled by default, got %d", cfg.CompressionThreshold)
+	}
+
+	t.Setenv("COMPRESSION_THRESHOLD", "256")
+	if cfg := FromEnv(); cfg.CompressionThreshold != 256 {
+		t.Fatalf("expected compression threshold 256, got %d", cfg.CompressionThreshold)
+	}
+}
+
+func TestFromEnv_OnlineMode(t *testing.T) {
+	if cfg := FromEnv(); cfg.OnlineMode || cfg.SessionServerURL != "https://sessionserver.mojang.com" {
+		t.Fatalf("expected offline mode with the Mojang session server by default, got %t %q", cfg.OnlineMode, cfg.SessionServerURL)
+	}
+
+	t.Setenv("ONLINE_MODE", "true")
+	t.Setenv("SESSION_SERVER_URL", "http://127.0.0.1:8080")
+
+	cfg := FromEnv()
+	if !cfg.OnlineMode {
+		t.Fatal("expected OnlineMode to be true")
+	}
+	if cfg.SessionServerURL != "http://127.0.0.1:8080" {
+		t.Fatalf("unexpected session server url: %q", cfg.SessionServerURL)
+	}
+}
+
+func TestFromEnv_MockSessionServer(t *testing.T) {
+	t.Setenv("MOCK_SESSION_SERVER_ADDR", "127.0.0.1:8652")
+	t.Setenv("MOCK_SESSION_ACCOUNTS", "Steve; Notch:069a79f444e94726a5befca90e38aaf5:http://textures.example/notch.png")
+
+	cfg := FromEnv()
+	if cfg.SessionServerURL != "http://127.0.0.1:8652" {
+		t.Fatalf("expected session server url to default to the mock server, got %q", cfg.SessionServerURL)
+	}
+	if len(cfg.MockSessionAccounts) != 2 {
+		t.Fatalf("expected 2 accounts, got %d", len(cfg.MockSessionAccounts))
+	}
+
+	steve := cfg.MockSessionAccounts[0]
+	if steve.Name != "Steve" || steve.UUID != protocol.OfflinePlayerUUID("Steve") || steve.SkinURL != "" {
+		t.Fatalf("unexpected account: %+v", steve)
+	}
+	notch := cfg.MockSessionAccounts[1]
+	if notch.UUID.String() != "069a79f4-44e9-4726-a5be-fca90e38aaf5" || notch.SkinURL != "http://textures.example/notch.png" {
+		t.Fatalf("unexpected account: %+v", notch)
+	}
+
+	t.Setenv("SESSION_SERVER_URL", "http://sessions.example")
+	if cfg := FromEnv(); cfg.SessionServerURL != "http://sessions.example" {
+		t.Fatalf("expected explicit session server url to win, got %q", cfg.SessionServerURL)
+	}
+}
+
+func TestFromEnv_LoginPluginRequests(t *testing.T) {
+	t.Setenv("LOGIN_PLUGIN_REQUESTS", "velocity:player_info=04; fml:loginwrapper ; bad:payload=zz")
+	t.Setenv("LOGIN_PLUGIN_REQUIRE_UNDERSTOOD", "true")
+
+	cfg := FromEnv()
+	if !cfg.LoginPluginRequireUnderstood {
+		t.Fatal("expected LoginPluginRequireUnderstood to be true")
+	}
+	if len(cfg.LoginPluginRequests) != 2 {
+		t.Fatalf("expected 2 login plugin requests, got %+v", cfg.LoginPluginRequests)
+	}
+
+	velocity := cfg.LoginPluginRequests[0]
+	if velocity.MessageID != 0 || velocity.Channel != "velocity:player_info" || !bytes.Equal(velocity.Data, []byte{0x04}) {
+		t.Fatalf("unexpected velocity request: %+v", velocity)
+	}
+	fml := cfg.LoginPluginRequests[1]
+	if fml.MessageID != 1 || fml.Channel != "fml:loginwrapper" || len(fml.Data) != 0 {
+		t.Fatalf("unexpected fml request: %+v", fml)
+	}
+}
+
+func TestConfigTransferTarget(t *testing.T) {
+	t.Setenv("TRANSFER_RULES", "Steve=lobby.example.com:25570; host:Play.Example.com=hub.example.com ; 069a79f444e94726a5befca90e38aaf5=[::1]:25566; *=fallback.example.com; Bad=host:notaport")
+
+	cfg := FromEnv()
+
+	cases := []struct {
+		username   string
+		playerUUID string
+		serverHost string
+		expected   string
+	}{
+		{username: "steve", serverHost: "play.example.com", expected: "lobby.example.com:25570"},
+		{username: "Alex", serverHost: "play.example.com", expected: "hub.example.com:25565"},
+		{username: "Notch", playerUUID: "069a79f4-44e9-4726-a5be-fca90e38aaf5", serverHost: "play.example.com", expected: "[::1]:25566"},
+		{username: "Alex", serverHost: "other.example.com", expected: "fallback.example.com:25565"},
+		{username: "Bad", expected: "fallback.example.com:25565"},
+	}
+
+	for _, tc := range cases {
+		target, ok := cfg.TransferTarget(tc.username, tc.playerUUID, tc.serverHost)
+		if !ok || target != tc.expected {
+			t.Fatalf("TransferTarget(%q, %q, %q): expected %q, got %q (%t)", tc.username, tc.playerUUID, tc.serverHost, tc.expected, target, ok)
+		}
+	}
+}
+
+func TestConfigTransferTarget_NoRules(t *testing.T) {
+	if _, ok := FromEnv().TransferTarget("Steve", "", ""); ok {
+		t.Fatal("expected no transfer target without rules")
+	}
+}
+
+func TestFromEnv_Cookies(t *testing.T) {
+	t.Setenv("COOKIE_REQUESTS", "minemock:session; minemock:queue ,")
+	t.Setenv("STORE_COOKIES", "minemock:session=lobby-1;minemock:empty")
+
+	cfg := FromEnv()
+	if len(cfg.CookieRequests) != 2 || cfg.CookieRequests[0] != "minemock:session" || cfg.CookieRequests[1] != "minemock:queue" {
+		t.Fatalf("unexpected cookie requests: %q", cfg.CookieRequests)
+	}
+	if len(cfg.StoreCookies) != 2 {
+		t.Fatalf("expected 2 cookies, got %+v", cfg.StoreCookies)
+	}
+	if cfg.StoreCookies[0].Key != "minemock:session" || string(cfg.StoreCookies[0].Payload) != "lobby-1" {
+		t.Fatalf("unexpected cookie: %+v", cfg.StoreCookies[0])
+	}
+	if cfg.StoreCookies[1].Key != "minemock:empty" || len(cfg.StoreCookies[1].Payload) != 0 {
+		t.Fatalf("unexpected cookie: %+v", cfg.StoreCookies[1])
+	}
+}
+
+func TestFromEnv_PlayerSample(t *testing.T) {
+	t.Setenv("PLAYER_SAMPLE", "Steve; Notch:069a79f444e94726a5befca90e38aaf5 ,")
+
+	cfg := FromEnv()
+	if len(cfg.PlayerSample) != 2 {
+		t.Fatalf("expected 2 sample players, got %d", len(cfg.PlayerSample))
+	}
+	if cfg.PlayerSample[0].Name != "Steve" || cfg.PlayerSample[0].ID != protocol.OfflinePlayerUUID("Steve").String() {
+		t.Fatalf("expected Steve with offline uuid, got %+v", cfg.PlayerSample[0])
+	}
+	if cfg.PlayerSample[1].Name != "Notch" || cfg.PlayerSample[1].ID != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Fatalf("expected Notch with explicit uuid, got %+v", cfg.PlayerSample[1])
+	}
+}
+
+func TestFromEnv_StatusChatFlags(t *testing.T) {
+	t.Setenv("ENFORCES_SECURE_CHAT", "true")
+	t.Setenv("PREVIEWS_CHAT", "1")
+	t.Setenv("FAVICON", "server-icon.png")
+
+	cfg := FromEnv()
+	if !cfg.EnforcesSecureChat || !cfg.PreviewsChat {
+		t.Fatalf("expected chat flags to be enabled, got %+v", cfg)
+	}
+	if cfg.FaviconPath != "server-icon.png" {
+		t.Fatalf("unexpected favicon path: %q", cfg.FaviconPath)
+	}
+}
+
+func TestFromEnv_AmpersandColorCodes(t *testing.T) {
+	t.Setenv("MOTD", "&aMine&lMock & friends")
+	t.Setenv("ERROR", "&cKicked")
+
+	cfg := FromEnv()
+	if cfg.MOTD != "&aMine&lMock & friends" {
+		t.Fatalf("expected ampersand codes to be kept by default, got %q", cfg.MOTD)
+	}
+
+	t.Setenv("AMPERSAND_COLOR_CODES", "true")
+
+	cfg = FromEnv()
+	if cfg.MOTD != "§aMine§lMock & friends" {
+		t.Fatalf("expected translated MOTD, got %q", cfg.MOTD)
+	}
+	if cfg.ErrorMessage != "§cKicked" {
+		t.Fatalf("expected translated ERROR, got %q", cfg.ErrorMessage)
+	}
+}
+
+func TestFromEnv_Limbo(t *testing.T) {
+	cfg := FromEnv()
+	if cfg.Limbo || cfg.LimboTitle != "§6Maintenance" || cfg.LimboChat != "" {
+		t.Fatalf("unexpected limbo defaults: %+v", cfg)
+	}
+
+	t.Setenv("LIMBO", "true")
+	t.Setenv("LIMBO_CHAT", "&eWe will be back\\nsoon")
+	t.Setenv("LIMBO_ACTIONBAR", "\\u00a77Waiting...")
+	t.Setenv("AMPERSAND_COLOR_CODES", "true")
+
+	cfg = FromEnv()
+	if !cfg.Limbo {
+		t.Fatal("expected limbo to be enabled")
+	}
+	if cfg.LimboChat != "§eWe will be back\nsoon" {
+		t.Fatalf("unexpected limbo chat: %q", cfg.LimboChat)
+	}
+	if cfg.LimboActionBar != "§7Waiting..." {
+		t.Fatalf("unexpected limbo action bar: %q", cfg.LimboActionBar)
+	}
+}
+
+func TestFromEnv_Queue(t *testing.T) {
+	cfg := FromEnv()
+	if cfg.Queue || cfg.QueueThroughput != 6 || cfg.QueueRelease != "transfer" || !cfg.QueueActionBar || cfg.QueueBossBar {
+		t.Fatalf("unexpected queue defaults: %+v", cfg)
+	}
+
+	t.Setenv("QUEUE", "true")
+	t.Setenv("QUEUE_THROUGHPUT", "30")
+	t.Setenv("QUEUE_RELEASE", "Proxy")
+	t.Setenv("QUEUE_DISPLAY", "both")
+
+	cfg = FromEnv()
+	if !cfg.Queue || cfg.QueueThroughput != 30 || cfg.QueueRelease != "proxy" || !cfg.QueueActionBar || !cfg.QueueBossBar {
+		t.Fatalf("unexpected queue config: %+v", cfg)
+	}
+
+	t.Setenv("QUEUE_THROUGHPUT", "0")
+	t.Setenv("QUEUE_RELEASE", "teleport")
+	t.Setenv("QUEUE_DISPLAY", "bossbar")
+
+	cfg = FromEnv()
+	if cfg.QueueThroughput != 6 || cfg.QueueRelease != "transfer" || cfg.QueueActionBar || !cfg.QueueBossBar {
+		t.Fatalf("expected invalid queue values to fall back, got %+v", cfg)
+	}
+}
+
+func TestFromEnv_KeepAliveAndPlayHold(t *testing.T) {
+	cfg := FromEnv()
+	if cfg.KeepAliveInterval != 10*time.Second || cfg.KeepAliveTimeout != 15*time.Second || cfg.PlayHoldTime != 0 {
+		t.Fatalf("unexpected keep alive defaults: %+v", cfg)
+	}
+
+	t.Setenv("KEEP_ALIVE_INTERVAL_SECONDS", "5")
+	t.Setenv("KEEP_ALIVE_TIMEOUT_SECONDS", "30")
+	t.Setenv("PLAY_HOLD_SECONDS", "60")
+
+	cfg = FromEnv()
+	if cfg.KeepAliveInterval != 5*time.Second || cfg.KeepAliveTimeout != 30*time.Second || cfg.PlayHoldTime != time.Minute {
+		t.Fatalf("unexpected keep alive config: %+v", cfg)
+	}
+
+	t.Setenv("KEEP_ALIVE_INTERVAL_SECONDS", "0")
+	t.Setenv("KEEP_ALIVE_TIMEOUT_SECONDS", "-1")
+
+	cfg = FromEnv()
+	if cfg.KeepAliveInterval != 10*time.Second || cfg.KeepAliveTimeout != 15*time.Second {
+		t.Fatalf("expected invalid keep alive values to fall back, got %+v", cfg)
+	}
+}
+
+func TestFromEnv_TimeoutsAndShutdown(t *testing.T) {
+	cfg := FromEnv()
+	if cfg.HandshakeTimeout != 5*time.Second || cfg.StatusTimeout != 10*time.Second || cfg.LoginTimeout != 30*time.Second || cfg.IdleTimeout != 30*time.Second {
+		t.Fatalf("unexpected timeout defaults: %+v", cfg)
+	}
+	if cfg.ShutdownMessage != "" || cfg.DrainTimeout != 30*time.Second {
+		t.Fatalf("unexpected shutdown defaults: %+v", cfg)
+	}
+
+	t.Setenv("HANDSHAKE_TIMEOUT_SECONDS", "0")
+	t.Setenv("IDLE_TIMEOUT_SECONDS", "120")
+	t.Setenv("DRAIN_TIMEOUT_SECONDS", "5")
+	t.Setenv("AMPERSAND_COLOR_CODES", "true")
+	t.Setenv("SHUTDOWN_MESSAGE", "&cRestarting")
+
+	cfg = FromEnv()
+	if cfg.HandshakeTimeout != 0 || cfg.IdleTimeout != 2*time.Minute || cfg.DrainTimeout != 5*time.Second {
+		t.Fatalf("unexpected timeouts: %+v", cfg)
+	}
+	if cfg.ShutdownMessage != "§cRestarting" {
+		t.Fatalf("unexpected shutdown message: %q", cfg.ShutdownMessage)
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoad_ConfigFileFormats(t *testing.T) {
+	files := map[string]string{
+		"minemock.yaml": "port: 25570\nmotd: From file\nlimbo: true\nplay_hold_seconds: 60\nlogin_whitelist: [Steve, Alex]\n",
+		"minemock.toml": "PORT = 25570\nMOTD = \"From file\"\nLIMBO = true\nPLAY_HOLD_SECONDS = 60\nLOGIN_WHITELIST = [\"Steve\", \"Alex\"]\n",
+		"minemock.json": `{"PORT": 25570, "MOTD": "From file", "LIMBO": true, "PLAY_HOLD_SECONDS": 60, "LOGIN_WHITELIST": ["Steve", "Alex"]}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load(writeConfigFile(t, name, content))
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Port != "25570" || cfg.MOTD != "From file" || !cfg.Limbo || cfg.PlayHoldTime != time.Minute {
+				t.Fatalf("unexpected config: %+v", cfg)
+			}
+			if _, ok := cfg.LoginWhitelist["alex"]; !ok || len(cfg.LoginWhitelist) != 2 {
+				t.Fatalf("unexpected whitelist: %v", cfg.LoginWhitelist)
+			}
+			if cfg.VersionName != defaultVersionName {
+				t.Fatalf("expected defaults for keys missing from the file, got %q", cfg.VersionName)
+			}
+		})
+	}
+}
+
+func TestLoad_ConfigFileFieldNames(t *testing.T) {
+	files := map[string]string{
+		"minemock.yaml": "MaxPlayers: 50\nPlayHoldTime: 60\nErrorMessage:\n  text: Closed\n  color: red\nMOTD: [{text: A}, {text: B}]\nLoginWhitelist: [Steve, Alex]\n",
+		"minemock.toml": "MaxPlayers = 50\nPlayHoldTime = 60\nMOTD = [{text = \"A\"}, {text = \"B\"}]\nLoginWhitelist = [\"Steve\", \"Alex\"]\n\n[ErrorMessage]\ntext = \"Closed\"\ncolor = \"red\"\n",
+		"minemock.json": `{"MaxPlayers": 50, "PlayHoldTime": 60, "ErrorMessage": {"text": "Closed", "color": "red"}, "MOTD": [{"text": "A"}, {"text": "B"}], "LoginWhitelist": ["Steve", "Alex"]}`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load(writeConfigFile(t, name, content))
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.MaxPlayers != 50 || cfg.PlayHoldTime != time.Minute || len(cfg.LoginWhitelist) != 2 {
+				t.Fatalf("unexpected config: %+v", cfg)
+			}
+			if cfg.ErrorMessage != `{"color":"red","text":"Closed"}` {
+				t.Fatalf("expected the error component as JSON, got %q", cfg.ErrorMessage)
+			}
+			if cfg.MOTD != `[{"text":"A"},{"text":"B"}]` {
+				t.Fatalf("expected the MOTD component as JSON, got %q", cfg.MOTD)
+			}
+		})
+	}
+
+	if _, err := Load(writeConfigFile(t, "minemock.yaml", "MaxPlayers: 50\nMAX_PLAYERS: 60\n")); err == nil {
+		t.Fatal("expected an error for a setting given twice")
+	}
+}
+
+func TestLoad_EnvironmentOverridesConfigFile(t *testing.T) {
+	path := writeConfigFile(t, "minemock.yaml", "PORT: 25570\nMOTD: From file\n")
+	t.Setenv("MINEMOCK_CONFIG", path)
+	t.Setenv("MOTD", "From env")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Port != "25570" || cfg.MOTD != "From env" {
+		t.Fatalf("expected the environment to override the file, got %+v", cfg)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected an error for a missing config file")
+	}
+	if _, err := Load(writeConfigFile(t, "minemock.ini", "PORT=1")); err == nil {
+		t.Fatal("expected an error for an unsupported extension")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := FromEnv().Validate(); err != nil {
+		t.Fatalf("expected the defaults to be valid, got %v", err)
+	}
+
+	t.Setenv("PORT", "abc")
+	t.Setenv("MAX_PLAYERS", "1e9")
+	t.Setenv("ONLINE_PLAYERS", "4294967296")
+	t.Setenv("LIMBO", "yes")
+	t.Setenv("SIMPLE_VOICECHAT_PORT", "70000")
+	t.Setenv("VERSION_NAME", "1.8.9")
+	t.Setenv("QUEUE_DISPLAY", "title")
+	t.Setenv("REAL_SERVER_ADDR", "127.0.0.1")
+	t.Setenv("PLAYER_SAMPLE", "Steve,Alex:not-a-uuid")
+	t.Setenv("MOCK_SESSION_ACCOUNTS", "Steve:123")
+	t.Setenv("STORE_COOKIES", "=value,minemock:token=abc")
+
+	cfg := FromEnv()
+	if cfg.MaxPlayers != defaultMaxPlayers || cfg.Limbo || cfg.Protocol != defaultProtocol {
+		t.Fatalf("expected invalid values to fall back, got %+v", cfg)
+	}
+
+	var invalid *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if len(cfg.PlayerSample) != 1 || len(cfg.MockSessionAccounts) != 0 || len(cfg.StoreCookies) != 1 {
+		t.Fatalf("expected invalid entries to be skipped, got %+v", cfg)
+	}
+	for _, key := range []string{"PORT", "MAX_PLAYERS", "ONLINE_PLAYERS", "LIMBO", "SIMPLE_VOICECHAT_PORT", "VERSION_NAME", "QUEUE_DISPLAY", "REAL_SERVER_ADDR", "PLAYER_SAMPLE", "MOCK_SESSION_ACCOUNTS", "STORE_COOKIES"} {
+		if !strings.Contains(invalid.Error(), key+"=") {
+			t.Errorf("expected a problem for %s in:\n%v", key, invalid)
+		}
+	}
+	if len(invalid.Problems) != 11 {
+		t.Fatalf("expected 11 problems, got %d:\n%v", len(invalid.Problems), invalid)
+	}
+
+	t.Setenv("PROTOCOL", "47")
+	if err := FromEnv().Validate(); strings.Contains(err.Error(), "VERSION_NAME") {
+		t.Fatalf("expected PROTOCOL to make any VERSION_NAME valid, got %v", err)
+	}
+}
+
+func TestLoad_UnknownConfigFileKeys(t *testing.T) {
+	cfg, err := Load(writeConfigFile(t, "minemock.yaml", "MAX_PLAYER: 50\nMaxPlayers: 40\n"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.MaxPlayers != 40 {
+		t.Fatalf("expected the known key to apply, got %d", cfg.MaxPlayers)
+	}
+
+	var invalid *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &invalid) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if len(invalid.Problems) != 1 || invalid.Problems[0] != `MAX_PLAYER="50": want a known setting` {
+		t.Fatalf("expected the unknown key to be reported, got %v", invalid)
+	}
+}
+
+func TestConfig_ValidateFavicon(t *testing.T) {
+	// A PNG header announcing a 16x16 image.
+	small := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n', 0, 0, 0, 13, 'I', 'H', 'D', 'R', 0, 0, 0, 16, 0, 0, 0, 16}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "missing file", path: filepath.Join(t.TempDir(), "missing.png"), want: "no such file"},
+		{name: "wrong size", path: writeConfigFile(t, "icon.png", string(small)), want: "must be 64x64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Defaults()
+			cfg.FaviconPath = tt.path
+
+			var invalid *ValidationError
+			if err := cfg.Validate(); !errors.As(err, &invalid) {
+				t.Fatalf("expected a ValidationError, got %v", err)
+			}
+			if len(invalid.Problems) != 1 || !strings.HasPrefix(invalid.Problems[0], "FAVICON=") || !strings.Contains(invalid.Problems[0], tt.want) {
+				t.Fatalf("expected a FAVICON problem with %q, got %v", tt.want, invalid)
+			}
+		})
+	}
+}
+
+func TestDefaults_IgnoreEnvironment(t *testing.T) {
+	t.Setenv("PORT", "25570")
+	t.Setenv("COMPRESSION_THRESHOLD", "256")
+
+	cfg := Defaults()
+	if cfg.Port != defaultPort || cfg.CompressionThreshold != defaultCompressionThreshold {
+		t.Fatalf("expected the defaults, got %+v", cfg)
+	}
+	if cfg := FromEnv(); cfg.Port != "25570" || cfg.CompressionThreshold != 256 {
+		t.Fatalf("expected FromEnv to read the environment, got %+v", cfg)
+	}
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// envConfig names the config file when Load is not given a path.
+const envConfig = "MINEMOCK_CONFIG"
+
+// source looks configuration values up by environment variable name: in the
+// environment first, then in the config file.
+type source struct {
+	// lookupEnv is os.LookupEnv, or nil to ignore the environment.
+	lookupEnv func(key string) (string, bool)
+	file      map[string]string
+	// unknownFileKeys holds the config file keys naming no setting, with their
+	// values, so config can report them.
+	unknownFileKeys map[string]string
+	// problems collects the values that were ignored, see invalid.
+	problems *[]string
+}
+
+// Load reads the configuration from the config file at path, or at
+// MINEMOCK_CONFIG when path is empty, with environment variables taking
+// precedence over the file and defaults applying to what neither sets.
+// Without a file it is FromEnv.
+func Load(path string) (Config, error) {
+	if path == "" {
+		path = strings.TrimSpace(os.Getenv(envConfig))
+	}
+	if path == "" {
+		return FromEnv(), nil
+	}
+
+	file, unknown, err := readConfigFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config file %s: %w", path, err)
+	}
+
+	return source{lookupEnv: os.LookupEnv, file: file, unknownFileKeys: unknown}.config(), nil
+}
+
+// fileKeys maps the config.Config field names accepted as config file keys to
+// the environment variable they stand for. Durations are given in seconds and
+// QueueDisplay selects QueueActionBar and QueueBossBar like QUEUE_DISPLAY.
+var fileKeys = map[string]string{
+	"IP":                           envIP,
+	"Port":                         envPort,
+	"ErrorMessage":                 envError,
+	"ErrorDelay":                   envErrorDelaySeconds,
+	"ForceConnectionLostTitle":     envForceConnectionLostTitle,
+	"MOTD":                         envMOTD,
+	"VersionName":                  envVersionName,
+	"Protocol":                     envProtocol,
+	"MaxPlayers":                   envMaxPlayers,
+	"OnlinePlayers":                envOnlinePlayers,
+	"FaviconPath":                  envFavicon,
+	"PlayerSample":                 envPlayerSample,
+	"EnforcesSecureChat":           envEnforcesSecureChat,
+	"PreviewsChat":                 envPreviewsChat,
+	"RealServerAddr":               envRealServerAddr,
+	"LoginWhitelist":               envLoginWhitelist,
+	"UseClientUUID":                envUseClientUUID,
+	"CompressionThreshold":         envCompressionThreshold,
+	"OnlineMode":                   envOnlineMode,
+	"SessionServerURL":             envSessionServerURL,
+	"MockSessionServerAddr":        envMockSessionServerAddr,
+	"MockSessionAccounts":          envMockSessionAccounts,
+	"LoginPluginRequests":          envLoginPluginRequests,
+	"LoginPluginRequireUnderstood": envLoginPluginRequireUnderstood,
+	"TransferRules":                envTransferRules,
+	"CookieRequests":               envCookieRequests,
+	"StoreCookies":                 envStoreCookies,
+	"Limbo":                        envLimbo,
+	"LimboTitle":                   envLimboTitle,
+	"LimboSubtitle":                envLimboSubtitle,
+	"LimboChat":                    envLimboChat,
+	"LimboActionBar":               envLimboActionBar,
+	"Queue":                        envQueue,
+	"QueueThroughput":              envQueueThroughput,
+	"QueueRelease":                 envQueueRelease,
+	"QueueDisplay":                 envQueueDisplay,
+	"QueueMessage":                 envQueueMessage,
+	"QueueReleaseMessage":          envQueueReleaseMessage,
+	"KeepAliveInterval":            envKeepAliveIntervalSeconds,
+	"KeepAliveTimeout":             envKeepAliveTimeoutSeconds,
+	"PlayHoldTime":                 envPlayHoldSeconds,
+	"HandshakeTimeout":             envHandshakeTimeoutSeconds,
+	"StatusTimeout":                envStatusTimeoutSeconds,
+	"LoginTimeout":                 envLoginTimeoutSeconds,
+	"IdleTimeout":                  envIdleTimeoutSeconds,
+	"ShutdownMessage":              envShutdownMessage,
+	"DrainTimeout":                 envDrainTimeoutSeconds,
+	"AmpersandColorCodes":          envAmpersandColorCodes,
+	"SimpleVoicechatPort":          envSimpleVoicechatPort,
+}
+
+// textComponentKeys are the settings that take a JSON text component. A table
+// or list given for one of them is encoded as JSON rather than flattened.
+var textComponentKeys = map[string]bool{
+	envError:               true,
+	envMOTD:                true,
+	envLimboTitle:          true,
+	envLimboSubtitle:       true,
+	envLimboChat:           true,
+	envLimboActionBar:      true,
+	envQueueMessage:        true,
+	envQueueReleaseMessage: true,
+	envShutdownMessage:     true,
+}
+
+// fileKeyNames resolves a config file key, compared by normalizeFileKey, to its
+// environment variable name. Both the field and the variable names resolve.
+var fileKeyNames = func() map[string]string {
+	names := make(map[string]string, 2*len(fileKeys))
+	for field, env := range fileKeys {
+		names[normalizeFileKey(field)] = env
+		names[normalizeFileKey(env)] = env
+	}
+	return names
+}()
+
+// normalizeFileKey makes "MaxPlayers", "max_players" and "MAX-PLAYERS" equal.
+func normalizeFileKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(key)))
+}
+
+// readConfigFile decodes a YAML, TOML or JSON file, chosen by extension, whose
+// keys are config.Config field names or environment variable names. The result
+// is keyed by environment variable name and values are turned into what the
+// variable would hold: lists are joined with commas and tables become
+// comma-separated key=value pairs, except for text components, which are
+// encoded as JSON. Keys naming no setting are returned apart, with their values.
+func readConfigFile(path string) (map[string]string, map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return nil, nil, fmt.Errorf("unsupported config file extension %q (want .yaml, .yml, .toml or .json)", ext)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string, len(raw))
+	unknown := map[string]string{}
+	for key, value := range raw {
+		env, ok := fileKeyNames[normalizeFileKey(key)]
+		if !ok {
+			unknown[key] = fmt.Sprint(value)
+			continue
+		}
+		if _, ok := values[env]; ok {
+			return nil, nil, fmt.Errorf("%s: %s is set twice", key, env)
+		}
+		text, err := configFileValue(env, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", key, err)
+		}
+		values[env] = text
+	}
+
+	return values, unknown, nil
+}
+
+func configFileValue(env string, value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case []any, map[string]any:
+		if textComponentKeys[env] {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		}
+	}
+
+	switch value := value.(type) {
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			text, err := configFileScalar(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		pairs := make([]string, 0, len(value))
+		for key, item := range value {
+			text, err := configFileScalar(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+"="+text)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	default:
+		return configFileScalar(value)
+	}
+}
+
+func configFileScalar(value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
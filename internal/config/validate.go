@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate returns a *ValidationError listing the values that were replaced
+// by their defaults while reading the configuration (unparsable numbers and
+// bools, out-of-range ports, unknown VERSION_NAME without PROTOCOL, ...) and
+// the settings MineMock cannot start with, such as a REAL_SERVER_ADDR that
+// does not resolve or a FAVICON that is not a 64x64 PNG. It returns nil when there are none.
+func (c Config) Validate() error {
+	problems := append([]string(nil), c.problems...)
+
+	if port, err := strconv.ParseUint(strings.TrimSpace(c.Port), 10, 16); err != nil || port == 0 {
+		problems = append(problems, fmt.Sprintf("%s=%q: want a port between 1 and 65535", envPort, c.Port))
+	}
+
+	if addr := strings.TrimSpace(c.RealServerAddr); addr != "" {
+		if _, err := net.ResolveTCPAddr("tcp", addr); err != nil {
+			problems = append(problems, fmt.Sprintf("%s=%q: %v", envRealServerAddr, addr, err))
+		}
+	}
+
+	if _, err := c.Favicon(); err != nil {
+		problems = append(problems, fmt.Sprintf("%s=%q: %v", envFavicon, c.FaviconPath, err))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: problems}
+}
+
+// Favicon reads FaviconPath and encodes it for the status response, or
+// returns "" when no favicon is set.
+func (c Config) Favicon() (string, error) {
+	if c.FaviconPath == "" {
+		return "", nil
+	}
+
+	png, err := os.ReadFile(c.FaviconPath)
+	if err != nil {
+		return "", err
+	}
+
+	return protocol.FaviconDataURI(png)
+}
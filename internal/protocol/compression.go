@@ -0,0 +1,139 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// maxUncompressedPacketLength is the vanilla limit for the "data length" of a
+// compressed packet (2^23 bytes).
+const maxUncompressedPacketLength = 8 * 1024 * 1024
+
+// SendSetCompression enables compression for every following packet in both
+// directions. Packets whose uncompressed size is below threshold are sent with
+// a data length of 0 and no zlib stream.
+func SendSetCompression(w io.Writer, threshold int32) error {
+	payload := make([]byte, 0, 1+5)
+	payload = append(payload, EncodeVarInt(loginPacketIDs.SetCompression)...)
+	payload = append(payload, EncodeVarInt(threshold)...)
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+// ReadCompressedPacket reads a packet framed with the compressed format and
+// returns the uncompressed packet (id and payload), like ReadPacket.
+func ReadCompressedPacket(r io.Reader, threshold int32) ([]byte, error) {
+	frame, err := ReadPacket(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressFrame(frame, threshold, maxUncompressedPacketLength)
+}
+
+// decompressFrame returns the packet carried by a compressed frame, refusing
+// to inflate more than maxLength bytes.
+func decompressFrame(frame []byte, threshold int32, maxLength int32) ([]byte, error) {
+	dataLength, n, err := decodeVarIntFromBytes(frame)
+	if err != nil {
+		return nil, fmt.Errorf("read data length: %w", err)
+	}
+	compressed := frame[n:]
+
+	if dataLength == 0 {
+		if len(compressed) == 0 {
+			return nil, fmt.Errorf("empty packet")
+		}
+		return compressed, nil
+	}
+	if dataLength < threshold {
+		return nil, fmt.Errorf("badly compressed packet: size %d is below threshold %d", dataLength, threshold)
+	}
+	if dataLength > maxUncompressedPacketLength {
+		return nil, fmt.Errorf("badly compressed packet: size %d is larger than protocol maximum %d", dataLength, maxUncompressedPacketLength)
+	}
+	if dataLength > maxLength {
+		return nil, &PacketTooLargeError{Length: dataLength, MaxLength: maxLength}
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, fmt.Errorf("open zlib stream: %w", err)
+	}
+	defer zr.Close()
+
+	packet, err := readFull(zr, int(dataLength))
+	if err != nil {
+		return nil, fmt.Errorf("inflate packet: %w", err)
+	}
+	if extra, _ := zr.Read(make([]byte, 1)); extra != 0 {
+		return nil, fmt.Errorf("badly compressed packet: more than %d bytes after inflating", dataLength)
+	}
+
+	return packet, nil
+}
+
+// WrapCompressedPacket frames an uncompressed packet (id and payload) in the
+// compressed format, deflating it when it reaches threshold.
+func WrapCompressedPacket(packet []byte, threshold int32) ([]byte, error) {
+	if len(packet) < int(threshold) {
+		frame := make([]byte, 0, 1+len(packet))
+		frame = append(frame, 0x00) // data length: not compressed
+		frame = append(frame, packet...)
+		return WrapPacket(frame), nil
+	}
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(packet); err != nil {
+		return nil, fmt.Errorf("deflate packet: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("deflate packet: %w", err)
+	}
+
+	frame := make([]byte, 0, 5+compressed.Len())
+	frame = append(frame, EncodeVarInt(int32(len(packet)))...)
+	frame = append(frame, compressed.Bytes()...)
+	return WrapPacket(frame), nil
+}
+
+// CompressedWriter re-frames the uncompressed packets written by the Send*
+// helpers in the compressed format. Every Write must contain whole packets.
+type CompressedWriter struct {
+	w         io.Writer
+	threshold int32
+}
+
+func NewCompressedWriter(w io.Writer, threshold int32) *CompressedWriter {
+	return &CompressedWriter{w: w, threshold: threshold}
+}
+
+func (c *CompressedWriter) Write(p []byte) (int, error) {
+	var out []byte
+	for rest := p; len(rest) > 0; {
+		length, n, err := decodeVarIntFromBytes(rest)
+		if err != nil {
+			return 0, fmt.Errorf("read packet length: %w", err)
+		}
+		if length <= 0 || len(rest) < n+int(length) {
+			return 0, fmt.Errorf("write is not a whole packet")
+		}
+
+		frame, err := WrapCompressedPacket(rest[n:n+int(length)], c.threshold)
+		if err != nil {
+			return 0, err
+		}
+		out = append(out, frame...)
+		rest = rest[n+int(length):]
+	}
+
+	if _, err := c.w.Write(out); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
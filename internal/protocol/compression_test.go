@@ -0,0 +1,83 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompressedPacketRoundTrip(t *testing.T) {
+	small := []byte{0x02, 0x01, 0x02}
+	large := append([]byte{0x1A}, bytes.Repeat([]byte("MineMock "), 64)...)
+
+	for _, packet := range [][]byte{small, large} {
+		framed, err := WrapCompressedPacket(packet, 256)
+		if err != nil {
+			t.Fatalf("WrapCompressedPacket failed: %v", err)
+		}
+
+		frame, err := ReadPacket(bytes.NewReader(framed))
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		dataLength, _, err := decodeVarIntFromBytes(frame)
+		if err != nil {
+			t.Fatalf("decode data length failed: %v", err)
+		}
+		if expectCompressed := len(packet) >= 256; expectCompressed != (dataLength != 0) {
+			t.Fatalf("packet of %d bytes: unexpected data length %d", len(packet), dataLength)
+		}
+
+		decoded, err := ReadCompressedPacket(bytes.NewReader(framed), 256)
+		if err != nil {
+			t.Fatalf("ReadCompressedPacket failed: %v", err)
+		}
+		if !bytes.Equal(decoded, packet) {
+			t.Fatalf("expected %v, got %v", packet, decoded)
+		}
+	}
+}
+
+func TestReadCompressedPacket_RejectsCompressedBelowThreshold(t *testing.T) {
+	framed, err := WrapCompressedPacket([]byte{0x00, 0x01}, 0)
+	if err != nil {
+		t.Fatalf("WrapCompressedPacket failed: %v", err)
+	}
+
+	if _, err := ReadCompressedPacket(bytes.NewReader(framed), 256); err == nil {
+		t.Fatal("expected packet compressed below the threshold to be rejected")
+	}
+}
+
+func TestCompressedWriter_ReframesSendHelpers(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendSetCompression(&out, 0); err != nil {
+		t.Fatalf("SendSetCompression failed: %v", err)
+	}
+
+	packet, err := ReadPacket(&out)
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if !bytes.Equal(packet, []byte{0x03, 0x00}) {
+		t.Fatalf("unexpected set compression packet: %v", packet)
+	}
+
+	if err := SendLoginDisconnect(NewCompressedWriter(&out, 0), "bye"); err != nil {
+		t.Fatalf("SendLoginDisconnect failed: %v", err)
+	}
+
+	packet, err = ReadCompressedPacket(&out, 0)
+	if err != nil {
+		t.Fatalf("ReadCompressedPacket failed: %v", err)
+	}
+	packetID, _, err := ReadPacketID(packet)
+	if err != nil {
+		t.Fatalf("ReadPacketID failed: %v", err)
+	}
+	if packetID != 0x00 {
+		t.Fatalf("unexpected login disconnect packet id: %d", packetID)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected a single packet, %d bytes left", out.Len())
+	}
+}
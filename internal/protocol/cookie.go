@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	maxCookieKeyBytes     = 32767 * 4
+	maxCookiePayloadBytes = 5120
+)
+
+// Cookie is a payload stored on the client under a namespaced key.
+type Cookie struct {
+	Key     string
+	Payload []byte
+}
+
+type CookieResponse struct {
+	Key string
+	// HasPayload is false when the client has no cookie stored for Key.
+	HasPayload bool
+	Payload    []byte
+}
+
+// SendLoginCookieRequest asks a 1.20.5+ client for the cookie stored under key,
+// typically by the server that transferred it here.
+func SendLoginCookieRequest(w io.Writer, protocolVersion int32, key string) error {
+	version, err := cookieVersion(protocolVersion)
+	if err != nil {
+		return err
+	}
+
+	return sendCookieRequest(w, version.Login.CookieRequest, key)
+}
+
+// SendConfigurationStoreCookie stores a cookie on the client. Clients
+// keep cookies across transfers, but not after they disconnect on their own.
+func SendConfigurationStoreCookie(w io.Writer, protocolVersion int32, cookie Cookie) error {
+	version, err := cookieVersion(protocolVersion)
+	if err != nil {
+		return err
+	}
+	if len(cookie.Payload) > maxCookiePayloadBytes {
+		return fmt.Errorf("cookie payload too long: %d", len(cookie.Payload))
+	}
+
+	packet := make([]byte, 0, 1+5+len(cookie.Key)+5+len(cookie.Payload))
+	packet = append(packet, EncodeVarInt(version.Configuration.StoreCookie)...)
+	packet = append(packet, EncodeVarInt(int32(len(cookie.Key)))...)
+	packet = append(packet, []byte(cookie.Key)...)
+	packet = append(packet, EncodeVarInt(int32(len(cookie.Payload)))...)
+	packet = append(packet, cookie.Payload...)
+
+	_, err = w.Write(WrapPacket(packet))
+	return err
+}
+
+func ReadLoginCookieResponse(packet []byte, protocolVersion int32) (CookieResponse, error) {
+	version, err := cookieVersion(protocolVersion)
+	if err != nil {
+		return CookieResponse{}, err
+	}
+
+	return readCookieResponse(packet, version.Login.CookieResponse)
+}
+
+func cookieVersion(protocolVersion int32) (Version, error) {
+	version := VersionFor(protocolVersion)
+	if !version.SupportsCookies {
+		return Version{}, fmt.Errorf("protocol %d does not support cookies", protocolVersion)
+	}
+
+	return version, nil
+}
+
+func sendCookieRequest(w io.Writer, packetID int32, key string) error {
+	payload := make([]byte, 0, 1+5+len(key))
+	payload = append(payload, EncodeVarInt(packetID)...)
+	payload = append(payload, EncodeVarInt(int32(len(key)))...)
+	payload = append(payload, []byte(key)...)
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+func readCookieResponse(packet []byte, expectedID int32) (CookieResponse, error) {
+	id, payload, err := ReadPacketID(packet)
+	if err != nil {
+		return CookieResponse{}, fmt.Errorf("read cookie response id: %w", err)
+	}
+	if id != expectedID {
+		return CookieResponse{}, fmt.Errorf("unexpected cookie response packet id: %d", id)
+	}
+
+	reader := payloadReader{data: payload}
+
+	var response CookieResponse
+	if response.Key, err = reader.string(maxCookieKeyBytes); err != nil {
+		return CookieResponse{}, fmt.Errorf("read cookie key: %w", err)
+	}
+	if response.HasPayload, err = reader.bool(); err != nil {
+		return CookieResponse{}, fmt.Errorf("read has payload flag: %w", err)
+	}
+	if response.HasPayload {
+		if response.Payload, err = reader.prefixedBytes(maxCookiePayloadBytes); err != nil {
+			return CookieResponse{}, fmt.Errorf("read cookie payload: %w", err)
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,55 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSendCookiePackets(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendLoginCookieRequest(&out, 766, "mm:a"); err != nil {
+		t.Fatalf("SendLoginCookieRequest failed: %v", err)
+	}
+	if err := SendConfigurationStoreCookie(&out, 766, Cookie{Key: "mm:a", Payload: []byte("hi")}); err != nil {
+		t.Fatalf("SendConfigurationStoreCookie failed: %v", err)
+	}
+
+	expected := [][]byte{
+		{0x05, 0x04, 'm', 'm', ':', 'a'},
+		{0x0A, 0x04, 'm', 'm', ':', 'a', 0x02, 'h', 'i'},
+	}
+	for _, want := range expected {
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		if !bytes.Equal(packet, want) {
+			t.Fatalf("expected packet %v, got %v", want, packet)
+		}
+	}
+
+	if err := SendLoginCookieRequest(&out, 765, "mm:a"); err == nil {
+		t.Fatal("expected cookies to be rejected before 1.20.5")
+	}
+	if err := SendConfigurationStoreCookie(&out, 766, Cookie{Key: "mm:a", Payload: make([]byte, 5121)}); err == nil {
+		t.Fatal("expected oversized cookie to be rejected")
+	}
+}
+
+func TestReadCookieResponse(t *testing.T) {
+	response, err := ReadLoginCookieResponse([]byte{0x04, 0x04, 'm', 'm', ':', 'a', 0x01, 0x02, 'h', 'i'}, 766)
+	if err != nil {
+		t.Fatalf("ReadLoginCookieResponse failed: %v", err)
+	}
+	if response.Key != "mm:a" || !response.HasPayload || string(response.Payload) != "hi" {
+		t.Fatalf("unexpected cookie response: %+v", response)
+	}
+
+	response, err = ReadLoginCookieResponse([]byte{0x04, 0x04, 'm', 'm', ':', 'a', 0x00}, 769)
+	if err != nil {
+		t.Fatalf("ReadLoginCookieResponse failed: %v", err)
+	}
+	if response.HasPayload || response.Payload != nil {
+		t.Fatalf("expected missing cookie, got %+v", response)
+	}
+}
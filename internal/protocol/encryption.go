@@ -0,0 +1,172 @@
+package protocol
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	maxServerIDBytes     = 20 * 4
+	maxSharedSecretBytes = 256
+	maxVerifyTokenBytes  = 256
+)
+
+type EncryptionRequest struct {
+	// ServerID is empty for every vanilla server since 1.7.
+	ServerID    string
+	PublicKey   []byte
+	VerifyToken []byte
+	// ShouldAuthenticate is only sent from 1.20.5; older clients always
+	// authenticate against the session server.
+	ShouldAuthenticate bool
+}
+
+type EncryptionResponse struct {
+	SharedSecret []byte
+	VerifyToken  []byte
+	// Salt and MessageSignature replace VerifyToken when a 1.19 - 1.19.2 client
+	// signs the verify token with its chat key instead of encrypting it.
+	Salt             int64
+	MessageSignature []byte
+}
+
+func SendEncryptionRequest(w io.Writer, protocolVersion int32, request EncryptionRequest) error {
+	version := VersionFor(protocolVersion)
+
+	payload := make([]byte, 0, 1+len(request.ServerID)+len(request.PublicKey)+len(request.VerifyToken)+16)
+	payload = append(payload, EncodeVarInt(version.Login.EncryptionRequest)...)
+	payload = append(payload, EncodeVarInt(int32(len(request.ServerID)))...)
+	payload = append(payload, []byte(request.ServerID)...)
+	payload = append(payload, EncodeVarInt(int32(len(request.PublicKey)))...)
+	payload = append(payload, request.PublicKey...)
+	payload = append(payload, EncodeVarInt(int32(len(request.VerifyToken)))...)
+	payload = append(payload, request.VerifyToken...)
+	if version.EncryptionRequestShouldAuthenticate {
+		if request.ShouldAuthenticate {
+			payload = append(payload, 0x01)
+		} else {
+			payload = append(payload, 0x00)
+		}
+	}
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+func ReadEncryptionResponse(packet []byte, protocolVersion int32) (EncryptionResponse, error) {
+	id, payload, err := ReadPacketID(packet)
+	if err != nil {
+		return EncryptionResponse{}, fmt.Errorf("read encryption response id: %w", err)
+	}
+	if id != loginPacketIDs.EncryptionResponse {
+		return EncryptionResponse{}, fmt.Errorf("unexpected encryption response packet id: %d", id)
+	}
+
+	reader := payloadReader{data: payload}
+
+	var response EncryptionResponse
+	if response.SharedSecret, err = reader.prefixedBytes(maxSharedSecretBytes); err != nil {
+		return EncryptionResponse{}, fmt.Errorf("read shared secret: %w", err)
+	}
+
+	if protocolVersion >= protocol1_19 && protocolVersion <= protocol1_19_2 {
+		hasVerifyToken, err := reader.bool()
+		if err != nil {
+			return EncryptionResponse{}, fmt.Errorf("read has verify token flag: %w", err)
+		}
+		if !hasVerifyToken {
+			if response.Salt, err = reader.int64(); err != nil {
+				return EncryptionResponse{}, fmt.Errorf("read salt: %w", err)
+			}
+			if response.MessageSignature, err = reader.prefixedBytes(maxSignatureBytes); err != nil {
+				return EncryptionResponse{}, fmt.Errorf("read message signature: %w", err)
+			}
+			return response, nil
+		}
+	}
+
+	if response.VerifyToken, err = reader.prefixedBytes(maxVerifyTokenBytes); err != nil {
+		return EncryptionResponse{}, fmt.Errorf("read verify token: %w", err)
+	}
+
+	return response, nil
+}
+
+// MinecraftServerHash returns the serverId sent to the session server: the
+// SHA-1 of the server id, shared secret and public key, printed as a signed
+// (two's complement) hexadecimal number like Java's BigInteger.toString(16).
+func MinecraftServerHash(serverID string, sharedSecret []byte, publicKey []byte) string {
+	hash := sha1.New()
+	hash.Write([]byte(serverID))
+	hash.Write(sharedSecret)
+	hash.Write(publicKey)
+	digest := hash.Sum(nil)
+
+	negative := digest[0]&0x80 != 0
+	if negative {
+		twosComplement := new(big.Int).SetBytes(digest)
+		twosComplement.Sub(new(big.Int).Lsh(big.NewInt(1), uint(len(digest)*8)), twosComplement)
+		return "-" + twosComplement.Text(16)
+	}
+
+	return strings.TrimLeft(hex.EncodeToString(digest), "0")
+}
+
+// NewEncryptionStreams returns the AES/CFB8 ciphers used after Encryption
+// Response: the shared secret is both the key and the IV, and each direction
+// keeps its own cipher state for the rest of the connection.
+func NewEncryptionStreams(sharedSecret []byte) (encrypt cipher.Stream, decrypt cipher.Stream, err error) {
+	encryptBlock, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create cipher: %w", err)
+	}
+	decryptBlock, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	return newCFB8(encryptBlock, sharedSecret, false), newCFB8(decryptBlock, sharedSecret, true), nil
+}
+
+// cfb8 is the 8-bit cipher feedback mode used by the protocol, which the
+// standard library does not provide.
+type cfb8 struct {
+	block    cipher.Block
+	register []byte
+	output   []byte
+	decrypt  bool
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) *cfb8 {
+	register := make([]byte, block.BlockSize())
+	copy(register, iv)
+
+	return &cfb8{
+		block:    block,
+		register: register,
+		output:   make([]byte, block.BlockSize()),
+		decrypt:  decrypt,
+	}
+}
+
+func (c *cfb8) XORKeyStream(dst, src []byte) {
+	last := len(c.register) - 1
+	for i, in := range src {
+		c.block.Encrypt(c.output, c.register)
+		out := in ^ c.output[0]
+
+		copy(c.register, c.register[1:])
+		if c.decrypt {
+			c.register[last] = in
+		} else {
+			c.register[last] = out
+		}
+		dst[i] = out
+	}
+}
@@ -0,0 +1,108 @@
+package protocol
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestMinecraftServerHash(t *testing.T) {
+	// Known digests of the player names, as used by wiki.vg to document the
+	// signed hexadecimal format.
+	cases := map[string]string{
+		"Notch": "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48",
+		"jeb_":  "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1",
+		"simon": "88e16a1019277b15d58faf0541e11910eb756f6",
+	}
+
+	for name, expected := range cases {
+		if got := MinecraftServerHash(name, nil, nil); got != expected {
+			t.Fatalf("MinecraftServerHash(%q): expected %s, got %s", name, expected, got)
+		}
+	}
+}
+
+func TestCFB8_MatchesNISTVector(t *testing.T) {
+	// NIST SP 800-38A, F.3.7 CFB8-AES128.Encrypt.
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	ciphertext, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("aes.NewCipher failed: %v", err)
+	}
+
+	encrypted := make([]byte, len(plaintext))
+	encrypter := newCFB8(block, iv, false)
+	// Split the input to check that the register carries over between calls.
+	encrypter.XORKeyStream(encrypted[:5], plaintext[:5])
+	encrypter.XORKeyStream(encrypted[5:], plaintext[5:])
+	if !bytes.Equal(encrypted, ciphertext) {
+		t.Fatalf("unexpected ciphertext: %x", encrypted)
+	}
+
+	decrypted := append([]byte(nil), ciphertext...)
+	newCFB8(block, iv, true).XORKeyStream(decrypted, decrypted)
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("unexpected plaintext: %x", decrypted)
+	}
+}
+
+func TestSendEncryptionRequest_ShouldAuthenticateLayout(t *testing.T) {
+	cases := map[int32]int{
+		763: 0,
+		766: 1,
+		769: 1,
+	}
+
+	for protocolVersion, trailingBytes := range cases {
+		var out bytes.Buffer
+		request := EncryptionRequest{PublicKey: []byte{0x30, 0x01}, VerifyToken: []byte{1, 2, 3, 4}, ShouldAuthenticate: true}
+		if err := SendEncryptionRequest(&out, protocolVersion, request); err != nil {
+			t.Fatalf("SendEncryptionRequest(%d) failed: %v", protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		packetID, payload, err := ReadPacketID(packet)
+		if err != nil {
+			t.Fatalf("ReadPacketID failed: %v", err)
+		}
+		if packetID != 0x01 {
+			t.Fatalf("unexpected encryption request packet id: %d", packetID)
+		}
+
+		expected := []byte{0x00, 0x02, 0x30, 0x01, 0x04, 1, 2, 3, 4}
+		if trailingBytes == 1 {
+			expected = append(expected, 0x01)
+		}
+		if !bytes.Equal(payload, expected) {
+			t.Fatalf("protocol %d: unexpected payload %v", protocolVersion, payload)
+		}
+	}
+}
+
+func TestReadEncryptionResponse(t *testing.T) {
+	packet := []byte{0x01, 0x02, 0xAA, 0xBB, 0x01, 0xCC}
+	response, err := ReadEncryptionResponse(packet, 763)
+	if err != nil {
+		t.Fatalf("ReadEncryptionResponse failed: %v", err)
+	}
+	if !bytes.Equal(response.SharedSecret, []byte{0xAA, 0xBB}) || !bytes.Equal(response.VerifyToken, []byte{0xCC}) {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	signed := []byte{0x01, 0x01, 0xAA, 0x00, 0, 0, 0, 0, 0, 0, 0, 0x07, 0x01, 0xDD}
+	response, err = ReadEncryptionResponse(signed, 759)
+	if err != nil {
+		t.Fatalf("ReadEncryptionResponse failed: %v", err)
+	}
+	if response.VerifyToken != nil || response.Salt != 7 || !bytes.Equal(response.MessageSignature, []byte{0xDD}) {
+		t.Fatalf("expected signed verify token, got %+v", response)
+	}
+}
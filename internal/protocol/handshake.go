@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	StateStatus   int32 = 1
+	StateLogin    int32 = 2
+	StateTransfer int32 = 3
+)
+
+type Handshake struct {
+	ProtocolVersion int32
+	// Host is the server address the client connected to, without the
+	// Forge marker, BungeeCord forwarding data and trailing SRV dot.
+	Host string
+	// RawHost is the server address field exactly as the client sent it.
+	RawHost   string
+	Port      uint16
+	NextState int32
+	// ForgeMarker is "FML", "FML2" or "FML3" for Forge clients.
+	ForgeMarker string
+	// BungeeCord is set when the host field carries BungeeCord IP forwarding.
+	BungeeCord *BungeeCordForwarding
+}
+
+type BungeeCordForwarding struct {
+	ClientIP string
+	UUID     string
+	// Properties is the raw JSON array of profile properties, if forwarded.
+	Properties string
+}
+
+func ReadHandshake(packet []byte) (Handshake, error) {
+	id, payload, err := ReadPacketID(packet)
+	if err != nil {
+		return Handshake{}, fmt.Errorf("read handshake id: %w", err)
+	}
+	if id != 0x00 {
+		return Handshake{}, fmt.Errorf("unexpected handshake packet id: %d", id)
+	}
+
+	protocolVersion, n, err := decodeVarIntFromBytes(payload)
+	if err != nil {
+		return Handshake{}, fmt.Errorf("read protocol version: %w", err)
+	}
+	payload = payload[n:]
+
+	hostLen, n, err := decodeVarIntFromBytes(payload)
+	if err != nil {
+		return Handshake{}, fmt.Errorf("read host len: %w", err)
+	}
+	payload = payload[n:]
+	if hostLen < 0 || len(payload) < int(hostLen)+2 {
+		return Handshake{}, fmt.Errorf("invalid host field")
+	}
+	rawHost := string(payload[:hostLen])
+	payload = payload[hostLen:]
+
+	if len(payload) < 2 {
+		return Handshake{}, fmt.Errorf("missing port")
+	}
+	port := binary.BigEndian.Uint16(payload[:2])
+	payload = payload[2:]
+
+	nextState, _, err := decodeVarIntFromBytes(payload)
+	if err != nil {
+		return Handshake{}, fmt.Errorf("read next state: %w", err)
+	}
+
+	handshake := Handshake{
+		ProtocolVersion: protocolVersion,
+		RawHost:         rawHost,
+		Port:            port,
+		NextState:       nextState,
+	}
+	handshake.Host, handshake.ForgeMarker, handshake.BungeeCord = splitHandshakeHost(rawHost)
+
+	return handshake, nil
+}
+
+// splitHandshakeHost separates the NUL-delimited suffixes that Forge
+// ("host\x00FML2\x00") and BungeeCord ("host\x00ip\x00uuid\x00properties")
+// append to the server address field.
+func splitHandshakeHost(rawHost string) (string, string, *BungeeCordForwarding) {
+	parts := strings.Split(rawHost, "\x00")
+	host := strings.TrimSuffix(parts[0], ".")
+
+	forgeMarker := ""
+	forwarded := make([]string, 0, 3)
+	for _, part := range parts[1:] {
+		if isForgeMarker(part) {
+			forgeMarker = part
+			continue
+		}
+		if part == "" {
+			continue
+		}
+		forwarded = append(forwarded, part)
+	}
+
+	if len(forwarded) < 2 {
+		return host, forgeMarker, nil
+	}
+
+	bungeeCord := &BungeeCordForwarding{
+		ClientIP: forwarded[0],
+		UUID:     forwarded[1],
+	}
+	if len(forwarded) > 2 {
+		bungeeCord.Properties = forwarded[2]
+	}
+
+	return host, forgeMarker, bungeeCord
+}
+
+func isForgeMarker(part string) bool {
+	if !strings.HasPrefix(part, "FML") {
+		return false
+	}
+
+	for _, r := range part[len("FML"):] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (h Handshake) IsForge() bool {
+	return h.ForgeMarker != ""
+}
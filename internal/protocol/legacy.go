@@ -0,0 +1,192 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// LegacyPingPacketID is the first byte sent by pre-1.7 clients (and many
+// monitoring tools) instead of a VarInt-framed handshake.
+const LegacyPingPacketID = 0xFE
+
+const (
+	legacyKickPacketID       = 0xFF
+	legacyPluginMessageID    = 0xFA
+	legacyPingHostChannel    = "MC|PingHost"
+	maxLegacyPingHostPayload = 2 * 512
+)
+
+type LegacyPingFormat int
+
+const (
+	// LegacyPingBeta is a bare 0xFE sent by Beta 1.8 - 1.3 clients.
+	LegacyPingBeta LegacyPingFormat = iota
+	// LegacyPing1_4 is 0xFE 0x01 sent by 1.4 - 1.5 clients.
+	LegacyPing1_4
+	// LegacyPing1_6 is 0xFE 0x01 followed by an MC|PingHost plugin message.
+	LegacyPing1_6
+)
+
+func (f LegacyPingFormat) String() string {
+	switch f {
+	case LegacyPingBeta:
+		return "beta"
+	case LegacyPing1_4:
+		return "1.4"
+	case LegacyPing1_6:
+		return "1.6"
+	default:
+		return "unknown"
+	}
+}
+
+type LegacyPing struct {
+	Format LegacyPingFormat
+	// ProtocolVersion, Host and Port are only sent by 1.6 clients.
+	ProtocolVersion byte
+	Host            string
+	Port            int32
+}
+
+type LegacyStatus struct {
+	Protocol      int32
+	VersionName   string
+	MOTD          string
+	OnlinePlayers int32
+	MaxPlayers    int32
+}
+
+// ReadLegacyPing reads the remainder of a legacy ping after the 0xFE byte.
+// The optional bytes that distinguish the formats are only sent by newer
+// clients, so a failed read there (typically a read deadline) ends the request
+// instead of failing it.
+func ReadLegacyPing(r io.Reader) (LegacyPing, error) {
+	var next [1]byte
+
+	if _, err := io.ReadFull(r, next[:]); err != nil || next[0] != 0x01 {
+		return LegacyPing{Format: LegacyPingBeta}, nil
+	}
+	if _, err := io.ReadFull(r, next[:]); err != nil || next[0] != legacyPluginMessageID {
+		return LegacyPing{Format: LegacyPing1_4}, nil
+	}
+
+	channel, err := readLegacyString(r)
+	if err != nil {
+		return LegacyPing{}, fmt.Errorf("read plugin channel: %w", err)
+	}
+	if channel != legacyPingHostChannel {
+		return LegacyPing{}, fmt.Errorf("unexpected plugin channel: %q", channel)
+	}
+
+	var dataLen uint16
+	if err := binary.Read(r, binary.BigEndian, &dataLen); err != nil {
+		return LegacyPing{}, fmt.Errorf("read plugin data length: %w", err)
+	}
+	if dataLen > maxLegacyPingHostPayload {
+		return LegacyPing{}, fmt.Errorf("plugin data too long: %d", dataLen)
+	}
+
+	data := make([]byte, dataLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return LegacyPing{}, fmt.Errorf("read plugin data: %w", err)
+	}
+
+	ping := LegacyPing{Format: LegacyPing1_6}
+	if len(data) < 1 {
+		return LegacyPing{}, fmt.Errorf("missing protocol version")
+	}
+	ping.ProtocolVersion = data[0]
+
+	dataReader := bytes.NewReader(data[1:])
+	if ping.Host, err = readLegacyString(dataReader); err != nil {
+		return LegacyPing{}, fmt.Errorf("read host: %w", err)
+	}
+	if err := binary.Read(dataReader, binary.BigEndian, &ping.Port); err != nil {
+		return LegacyPing{}, fmt.Errorf("read port: %w", err)
+	}
+
+	return ping, nil
+}
+
+// SendLegacyKick answers a legacy ping with the kick packet that pre-1.7
+// clients parse as a server list entry.
+func SendLegacyKick(w io.Writer, format LegacyPingFormat, status LegacyStatus) error {
+	motd := strings.ReplaceAll(status.MOTD, "\n", " ")
+
+	var response string
+	if format == LegacyPingBeta {
+		// Beta clients split on the section sign, so the MOTD cannot carry colors.
+		response = strings.Join([]string{
+			StripLegacyFormatting(motd),
+			strconv.Itoa(int(status.OnlinePlayers)),
+			strconv.Itoa(int(status.MaxPlayers)),
+		}, "§")
+	} else {
+		response = strings.Join([]string{
+			"§1",
+			strconv.Itoa(int(status.Protocol)),
+			status.VersionName,
+			motd,
+			strconv.Itoa(int(status.OnlinePlayers)),
+			strconv.Itoa(int(status.MaxPlayers)),
+		}, "\x00")
+	}
+
+	encoded := utf16.Encode([]rune(response))
+	if len(encoded) > 0xFFFF {
+		return fmt.Errorf("legacy kick message too long: %d", len(encoded))
+	}
+
+	packet := make([]byte, 0, 3+2*len(encoded))
+	packet = append(packet, legacyKickPacketID)
+	packet = binary.BigEndian.AppendUint16(packet, uint16(len(encoded)))
+	for _, unit := range encoded {
+		packet = binary.BigEndian.AppendUint16(packet, unit)
+	}
+
+	_, err := w.Write(packet)
+	return err
+}
+
+// StripLegacyFormatting removes section sign formatting codes from text.
+func StripLegacyFormatting(text string) string {
+	var builder strings.Builder
+	builder.Grow(len(text))
+
+	skipNext := false
+	for _, r := range text {
+		if skipNext {
+			skipNext = false
+			continue
+		}
+		if r == '§' {
+			skipNext = true
+			continue
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
+
+func readLegacyString(r io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if int(length) > maxLegacyPingHostPayload {
+		return "", fmt.Errorf("string too long: %d", length)
+	}
+
+	units := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return "", err
+	}
+
+	return string(utf16.Decode(units)), nil
+}
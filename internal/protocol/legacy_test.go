@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func legacyString(value string) []byte {
+	units := utf16.Encode([]rune(value))
+	out := binary.BigEndian.AppendUint16(nil, uint16(len(units)))
+	for _, unit := range units {
+		out = binary.BigEndian.AppendUint16(out, unit)
+	}
+	return out
+}
+
+func decodeLegacyKick(t *testing.T, packet []byte) string {
+	t.Helper()
+
+	if len(packet) < 3 || packet[0] != 0xFF {
+		t.Fatalf("expected legacy kick packet, got %x", packet)
+	}
+	length := int(binary.BigEndian.Uint16(packet[1:3]))
+	if len(packet) != 3+2*length {
+		t.Fatalf("kick length mismatch: declared %d chars, got %d bytes", length, len(packet)-3)
+	}
+
+	units := make([]uint16, length)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(packet[3+2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func TestReadLegacyPing_Formats(t *testing.T) {
+	pingHostData := []byte{78}
+	pingHostData = append(pingHostData, legacyString("localhost")...)
+	pingHostData = binary.BigEndian.AppendUint32(pingHostData, 25565)
+
+	pingHost := []byte{0x01, 0xFA}
+	pingHost = append(pingHost, legacyString("MC|PingHost")...)
+	pingHost = binary.BigEndian.AppendUint16(pingHost, uint16(len(pingHostData)))
+	pingHost = append(pingHost, pingHostData...)
+
+	cases := []struct {
+		name   string
+		data   []byte
+		format LegacyPingFormat
+	}{
+		{name: "beta", data: nil, format: LegacyPingBeta},
+		{name: "1.4", data: []byte{0x01}, format: LegacyPing1_4},
+		{name: "1.6", data: pingHost, format: LegacyPing1_6},
+	}
+
+	for _, tc := range cases {
+		ping, err := ReadLegacyPing(bytes.NewReader(tc.data))
+		if err != nil {
+			t.Fatalf("%s: ReadLegacyPing failed: %v", tc.name, err)
+		}
+		if ping.Format != tc.format {
+			t.Fatalf("%s: expected format %d, got %d", tc.name, tc.format, ping.Format)
+		}
+	}
+
+	ping, err := ReadLegacyPing(bytes.NewReader(pingHost))
+	if err != nil {
+		t.Fatalf("ReadLegacyPing failed: %v", err)
+	}
+	if ping.ProtocolVersion != 78 || ping.Host != "localhost" || ping.Port != 25565 {
+		t.Fatalf("unexpected ping host data: %+v", ping)
+	}
+}
+
+func TestSendLegacyKick(t *testing.T) {
+	status := LegacyStatus{
+		Protocol:      763,
+		VersionName:   "1.20.1",
+		MOTD:          "§aMineMock\nTest",
+		OnlinePlayers: 7,
+		MaxPlayers:    20,
+	}
+
+	var out bytes.Buffer
+	if err := SendLegacyKick(&out, LegacyPing1_6, status); err != nil {
+		t.Fatalf("SendLegacyKick failed: %v", err)
+	}
+	fields := strings.Split(decodeLegacyKick(t, out.Bytes()), "\x00")
+	expected := []string{"§1", "763", "1.20.1", "§aMineMock Test", "7", "20"}
+	if strings.Join(fields, "|") != strings.Join(expected, "|") {
+		t.Fatalf("unexpected legacy kick fields: %q", fields)
+	}
+
+	out.Reset()
+	if err := SendLegacyKick(&out, LegacyPingBeta, status); err != nil {
+		t.Fatalf("SendLegacyKick failed: %v", err)
+	}
+	if got := decodeLegacyKick(t, out.Bytes()); got != "MineMock Test§7§20" {
+		t.Fatalf("unexpected beta kick: %q", got)
+	}
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import "fmt"
+
+const (
+	maxUsernameBytes  = 16 * 4
+	maxPublicKeyBytes = 512
+	maxSignatureBytes = 4096
+)
+
+type LoginStart struct {
+	Username string
+	// UUID is the player UUID reported by the client; only meaningful when
+	// HasUUID is true (optional for 1.19.1 - 1.20.1, always sent from 1.20.2).
+	UUID    UUID
+	HasUUID bool
+	// Signature is the chat signing key sent by 1.19 - 1.19.2 clients.
+	Signature *LoginSignature
+}
+
+type LoginSignature struct {
+	ExpiresAt int64
+	PublicKey []byte
+	Signature []byte
+}
+
+func ReadLoginStart(packet []byte, protocolVersion int32) (LoginStart, error) {
+	id, payload, err := ReadPacketID(packet)
+	if err != nil {
+		return LoginStart{}, fmt.Errorf("read login start id: %w", err)
+	}
+	if id != 0x00 {
+		return LoginStart{}, fmt.Errorf("unexpected login start packet id: %d", id)
+	}
+
+	reader := payloadReader{data: payload}
+
+	username, err := reader.string(maxUsernameBytes)
+	if err != nil {
+		return LoginStart{}, fmt.Errorf("read username: %w", err)
+	}
+	if username == "" {
+		return LoginStart{}, fmt.Errorf("invalid username length")
+	}
+
+	loginStart := LoginStart{Username: username}
+
+	if protocolVersion >= protocol1_19 && protocolVersion <= protocol1_19_2 {
+		signature, err := readLoginSignature(&reader)
+		if err != nil {
+			return LoginStart{}, err
+		}
+		loginStart.Signature = signature
+	}
+
+	switch {
+	case protocolVersion >= protocol1_20_2:
+		loginStart.UUID, err = reader.uuid()
+		if err != nil {
+			return LoginStart{}, fmt.Errorf("read player uuid: %w", err)
+		}
+		loginStart.HasUUID = true
+	case protocolVersion >= protocol1_19_2:
+		loginStart.HasUUID, err = reader.bool()
+		if err != nil {
+			return LoginStart{}, fmt.Errorf("read has uuid flag: %w", err)
+		}
+		if loginStart.HasUUID {
+			loginStart.UUID, err = reader.uuid()
+			if err != nil {
+				return LoginStart{}, fmt.Errorf("read player uuid: %w", err)
+			}
+		}
+	}
+
+	return loginStart, nil
+}
+
+func readLoginSignature(reader *payloadReader) (*LoginSignature, error) {
+	hasSignature, err := reader.bool()
+	if err != nil {
+		return nil, fmt.Errorf("read has signature flag: %w", err)
+	}
+	if !hasSignature {
+		return nil, nil
+	}
+
+	expiresAt, err := reader.int64()
+	if err != nil {
+		return nil, fmt.Errorf("read signature timestamp: %w", err)
+	}
+	publicKey, err := reader.prefixedBytes(maxPublicKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	signature, err := reader.prefixedBytes(maxSignatureBytes)
+	if err != nil {
+		return nil, fmt.Errorf("read signature: %w", err)
+	}
+
+	return &LoginSignature{
+		ExpiresAt: expiresAt,
+		PublicKey: publicKey,
+		Signature: signature,
+	}, nil
+}
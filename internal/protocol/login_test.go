@@ -0,0 +1,92 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func loginStartPacket(username string, fields ...[]byte) []byte {
+	packet := append(EncodeVarInt(0x00), EncodeVarInt(int32(len(username)))...)
+	packet = append(packet, []byte(username)...)
+	for _, field := range fields {
+		packet = append(packet, field...)
+	}
+	return packet
+}
+
+func TestReadLoginStart_VersionLayouts(t *testing.T) {
+	playerUUID := OfflinePlayerUUID("Steve")
+
+	cases := []struct {
+		name            string
+		protocolVersion int32
+		packet          []byte
+		hasUUID         bool
+	}{
+		{name: "1.19 without signature", protocolVersion: 759, packet: loginStartPacket("Steve", []byte{0x00}), hasUUID: false},
+		{name: "1.19.2 with uuid", protocolVersion: 760, packet: loginStartPacket("Steve", []byte{0x00, 0x01}, playerUUID[:]), hasUUID: true},
+		{name: "1.20.1 without uuid", protocolVersion: 763, packet: loginStartPacket("Steve", []byte{0x00}), hasUUID: false},
+		{name: "1.20.1 with uuid", protocolVersion: 763, packet: loginStartPacket("Steve", []byte{0x01}, playerUUID[:]), hasUUID: true},
+		{name: "1.20.2 uuid", protocolVersion: 764, packet: loginStartPacket("Steve", playerUUID[:]), hasUUID: true},
+	}
+
+	for _, tc := range cases {
+		loginStart, err := ReadLoginStart(tc.packet, tc.protocolVersion)
+		if err != nil {
+			t.Fatalf("%s: ReadLoginStart failed: %v", tc.name, err)
+		}
+		if loginStart.Username != "Steve" {
+			t.Fatalf("%s: unexpected username %q", tc.name, loginStart.Username)
+		}
+		if loginStart.HasUUID != tc.hasUUID {
+			t.Fatalf("%s: expected has uuid %t, got %t", tc.name, tc.hasUUID, loginStart.HasUUID)
+		}
+		if loginStart.HasUUID && loginStart.UUID != playerUUID {
+			t.Fatalf("%s: unexpected uuid %s", tc.name, loginStart.UUID)
+		}
+	}
+}
+
+func TestReadLoginStart_SignatureData(t *testing.T) {
+	publicKey := []byte{0x30, 0x82, 0x01}
+	signature := []byte{0xAA, 0xBB}
+
+	expiresAt := make([]byte, 8)
+	binary.BigEndian.PutUint64(expiresAt, 1700000000000)
+
+	packet := loginStartPacket(
+		"Steve",
+		[]byte{0x01},
+		expiresAt,
+		EncodeVarInt(int32(len(publicKey))), publicKey,
+		EncodeVarInt(int32(len(signature))), signature,
+		[]byte{0x00},
+	)
+
+	loginStart, err := ReadLoginStart(packet, 760)
+	if err != nil {
+		t.Fatalf("ReadLoginStart failed: %v", err)
+	}
+	if loginStart.Signature == nil {
+		t.Fatal("expected signature data")
+	}
+	if loginStart.Signature.ExpiresAt != 1700000000000 {
+		t.Fatalf("unexpected expiry: %d", loginStart.Signature.ExpiresAt)
+	}
+	if !bytes.Equal(loginStart.Signature.PublicKey, publicKey) || !bytes.Equal(loginStart.Signature.Signature, signature) {
+		t.Fatalf("unexpected signature data: %+v", loginStart.Signature)
+	}
+	if loginStart.HasUUID {
+		t.Fatal("expected no uuid")
+	}
+}
+
+func TestReadLoginStart_RejectsTruncatedUUID(t *testing.T) {
+	if _, err := ReadLoginStart(loginStartPacket("Steve"), 764); err == nil {
+		t.Fatal("expected error for 1.20.2 login start without uuid")
+	}
+	if _, err := ReadLoginStart(loginStartPacket("Steve", []byte{0x01, 0x02}), 763); err == nil {
+		t.Fatal("expected error for truncated uuid")
+	}
+}
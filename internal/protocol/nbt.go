@@ -0,0 +1,240 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf16"
+)
+
+const (
+	nbtTagEnd      byte = 0
+	nbtTagByte     byte = 1
+	nbtTagInt      byte = 3
+	nbtTagDouble   byte = 6
+	nbtTagString   byte = 8
+	nbtTagList     byte = 9
+	nbtTagCompound byte = 10
+)
+
+// appendNetworkNBT appends value as network NBT: the root tag type followed by
+// its payload, without the root name used by the file format. Supported values
+// are map[string]any (compound), []any (list), string, bool (byte), int8
+// (byte), int32 (int) and float64 (int when integral, double otherwise), which
+// covers everything produced by decoding a JSON text component.
+func appendNetworkNBT(dst []byte, value any) ([]byte, error) {
+	tagType, err := nbtTagType(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, tagType)
+	return appendNBTPayload(dst, value)
+}
+
+// appendNamedNBT appends value with an empty root name, the format used on
+// the network before 1.20.2.
+func appendNamedNBT(dst []byte, value any) ([]byte, error) {
+	tagType, err := nbtTagType(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, tagType, 0x00, 0x00)
+	return appendNBTPayload(dst, value)
+}
+
+// TextComponentNBT encodes a chat message the way 1.20.3+ clients expect text
+// components in play and configuration packets: § formatted text and JSON
+// components are both converted to an NBT compound.
+func TextComponentNBT(message string) ([]byte, error) {
+	payload, err := textComponentPayload(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("decode text component: %w", err)
+	}
+
+	return appendNetworkNBT(nil, nbtTextComponent(decoded))
+}
+
+// nbtTextComponent makes a decoded JSON component representable as NBT. Lists
+// must hold a single tag type, so plain strings next to compounds are wrapped
+// as {"text": ...} the same way the client reads them from JSON.
+func nbtTextComponent(value any) any {
+	switch component := value.(type) {
+	case string:
+		return map[string]any{"text": component}
+	case []any:
+		if len(component) == 0 {
+			return map[string]any{"text": ""}
+		}
+		// The first element is the parent of the rest, as in JSON.
+		root := nbtTextComponent(component[0]).(map[string]any)
+		if len(component) > 1 {
+			extra, _ := root["extra"].([]any)
+			root["extra"] = append(extra, nbtTextComponentList(component[1:])...)
+		}
+		return root
+	case map[string]any:
+		for key, child := range component {
+			if list, ok := child.([]any); ok && (key == "extra" || key == "with") {
+				component[key] = nbtTextComponentList(list)
+			}
+		}
+		return component
+	default:
+		return map[string]any{"text": fmt.Sprint(component)}
+	}
+}
+
+func nbtTextComponentList(list []any) []any {
+	converted := make([]any, len(list))
+	for i, child := range list {
+		converted[i] = nbtTextComponent(child)
+	}
+	return converted
+}
+
+func nbtTagType(value any) (byte, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		return nbtTagCompound, nil
+	case []any:
+		return nbtTagList, nil
+	case string:
+		return nbtTagString, nil
+	case bool, int8:
+		return nbtTagByte, nil
+	case int32:
+		return nbtTagInt, nil
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+			return nbtTagInt, nil
+		}
+		return nbtTagDouble, nil
+	default:
+		return 0, fmt.Errorf("unsupported nbt value: %T", value)
+	}
+}
+
+func appendNBTPayload(dst []byte, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		return appendNBTCompound(dst, v)
+	case []any:
+		return appendNBTList(dst, v)
+	case string:
+		return appendNBTString(dst, v)
+	case bool:
+		if v {
+			return append(dst, 1), nil
+		}
+		return append(dst, 0), nil
+	case int8:
+		return append(dst, byte(v)), nil
+	case int32:
+		return binary.BigEndian.AppendUint32(dst, uint32(v)), nil
+	case float64:
+		tagType, _ := nbtTagType(v)
+		if tagType == nbtTagInt {
+			return binary.BigEndian.AppendUint32(dst, uint32(int32(v))), nil
+		}
+		return binary.BigEndian.AppendUint64(dst, math.Float64bits(v)), nil
+	default:
+		return nil, fmt.Errorf("unsupported nbt value: %T", value)
+	}
+}
+
+func appendNBTCompound(dst []byte, compound map[string]any) ([]byte, error) {
+	// Sorted keys keep the encoding deterministic.
+	keys := make([]string, 0, len(compound))
+	for key := range compound {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		tagType, err := nbtTagType(compound[key])
+		if err != nil {
+			return nil, fmt.Errorf("compound key %q: %w", key, err)
+		}
+
+		dst = append(dst, tagType)
+		if dst, err = appendNBTString(dst, key); err != nil {
+			return nil, err
+		}
+		if dst, err = appendNBTPayload(dst, compound[key]); err != nil {
+			return nil, fmt.Errorf("compound key %q: %w", key, err)
+		}
+	}
+
+	return append(dst, nbtTagEnd), nil
+}
+
+func appendNBTList(dst []byte, list []any) ([]byte, error) {
+	if len(list) == 0 {
+		dst = append(dst, nbtTagEnd)
+		return binary.BigEndian.AppendUint32(dst, 0), nil
+	}
+
+	elementType, err := nbtTagType(list[0])
+	if err != nil {
+		return nil, err
+	}
+
+	dst = append(dst, elementType)
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(list)))
+	for i, element := range list {
+		tagType, err := nbtTagType(element)
+		if err != nil {
+			return nil, err
+		}
+		if tagType != elementType {
+			return nil, fmt.Errorf("nbt list element %d has tag %d, expected %d", i, tagType, elementType)
+		}
+		if dst, err = appendNBTPayload(dst, element); err != nil {
+			return nil, err
+		}
+	}
+
+	return dst, nil
+}
+
+// appendNBTString writes a string in Java's modified UTF-8: NUL is encoded as
+// two bytes and supplementary characters as a pair of three byte surrogates.
+func appendNBTString(dst []byte, value string) ([]byte, error) {
+	encoded := make([]byte, 0, len(value))
+	for _, r := range value {
+		if r > 0xFFFF {
+			high, low := utf16.EncodeRune(r)
+			encoded = appendModifiedUTF8Unit(encoded, high)
+			encoded = appendModifiedUTF8Unit(encoded, low)
+			continue
+		}
+		encoded = appendModifiedUTF8Unit(encoded, r)
+	}
+
+	if len(encoded) > math.MaxUint16 {
+		return nil, fmt.Errorf("nbt string too long: %d bytes", len(encoded))
+	}
+
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(encoded)))
+	return append(dst, encoded...), nil
+}
+
+func appendModifiedUTF8Unit(dst []byte, unit rune) []byte {
+	switch {
+	case unit >= 0x01 && unit <= 0x7F:
+		return append(dst, byte(unit))
+	case unit <= 0x7FF:
+		return append(dst, byte(0xC0|unit>>6), byte(0x80|unit&0x3F))
+	default:
+		return append(dst, byte(0xE0|unit>>12), byte(0x80|unit>>6&0x3F), byte(0x80|unit&0x3F))
+	}
+}
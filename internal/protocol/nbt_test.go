@@ -0,0 +1,105 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAppendNetworkNBT_Compound(t *testing.T) {
+	encoded, err := appendNetworkNBT(nil, map[string]any{
+		"text": "hi",
+		"bold": true,
+	})
+	if err != nil {
+		t.Fatalf("appendNetworkNBT failed: %v", err)
+	}
+
+	expected := []byte{
+		nbtTagCompound,
+		nbtTagByte, 0x00, 0x04, 'b', 'o', 'l', 'd', 0x01,
+		nbtTagString, 0x00, 0x04, 't', 'e', 'x', 't', 0x00, 0x02, 'h', 'i',
+		nbtTagEnd,
+	}
+	if !bytes.Equal(encoded, expected) {
+		t.Fatalf("unexpected nbt:\n got %v\nwant %v", encoded, expected)
+	}
+}
+
+func TestAppendNetworkNBT_ListAndModifiedUTF8(t *testing.T) {
+	encoded, err := appendNetworkNBT(nil, []any{"a\x00", "😀"})
+	if err != nil {
+		t.Fatalf("appendNetworkNBT failed: %v", err)
+	}
+
+	expected := []byte{
+		nbtTagList, nbtTagString, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x03, 'a', 0xC0, 0x80,
+		0x00, 0x06, 0xED, 0xA0, 0xBD, 0xED, 0xB8, 0x80,
+	}
+	if !bytes.Equal(encoded, expected) {
+		t.Fatalf("unexpected nbt:\n got %v\nwant %v", encoded, expected)
+	}
+
+	if _, err := appendNetworkNBT(nil, []any{"a", true}); err == nil {
+		t.Fatal("expected mixed list to be rejected")
+	}
+}
+
+func TestTextComponentNBT_WrapsMixedExtra(t *testing.T) {
+	encoded, err := TextComponentNBT(`["", "plain", {"text": "red", "color": "red"}]`)
+	if err != nil {
+		t.Fatalf("TextComponentNBT failed: %v", err)
+	}
+
+	expected, err := appendNetworkNBT(nil, map[string]any{
+		"text": "",
+		"extra": []any{
+			map[string]any{"text": "plain"},
+			map[string]any{"text": "red", "color": "red"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("appendNetworkNBT failed: %v", err)
+	}
+	if !bytes.Equal(encoded, expected) {
+		t.Fatalf("unexpected nbt:\n got %v\nwant %v", encoded, expected)
+	}
+}
+
+func TestSendPlayDisconnect_UsesNBTFrom1_20_3(t *testing.T) {
+	expectedReason, err := TextComponentNBT("bye")
+	if err != nil {
+		t.Fatalf("TextComponentNBT failed: %v", err)
+	}
+
+	for _, protocolVersion := range []int32{765, 766, 769} {
+		var out bytes.Buffer
+		if err := SendPlayDisconnect(&out, protocolVersion, "bye"); err != nil {
+			t.Fatalf("SendPlayDisconnect(%d) failed: %v", protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		_, payload, err := ReadPacketID(packet)
+		if err != nil {
+			t.Fatalf("ReadPacketID failed: %v", err)
+		}
+		if !bytes.Equal(payload, expectedReason) {
+			t.Fatalf("protocol %d: expected nbt reason %v, got %v", protocolVersion, expectedReason, payload)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := SendConfigurationDisconnect(&out, 765, "bye"); err != nil {
+		t.Fatalf("SendConfigurationDisconnect failed: %v", err)
+	}
+	packet, err := ReadPacket(&out)
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if _, payload, _ := ReadPacketID(packet); !bytes.Equal(payload, expectedReason) {
+		t.Fatalf("expected nbt configuration disconnect reason, got %v", payload)
+	}
+}
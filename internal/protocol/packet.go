@@ -1,7 +1,6 @@
 package protocol
 
 import (
-	"crypto/rand"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -9,35 +8,10 @@ import (
 	"strings"
 )
 
-type StatusResponse struct {
-	Version struct {
-		Name     string `json:"name"`
-		Protocol int32  `json:"protocol"`
-	} `json:"version"`
-	Players struct {
-		Max    int32 `json:"max"`
-		Online int32 `json:"online"`
-	} `json:"players"`
-	Description struct {
-		Text string `json:"text"`
-	} `json:"description"`
-}
-
+// ReadPacket reads one uncompressed packet of at most MaxPacketLength bytes.
+// Use a PacketReader for the tighter limits of the earlier states.
 func ReadPacket(r io.Reader) ([]byte, error) {
-	length, err := ReadVarInt(r)
-	if err != nil {
-		return nil, err
-	}
-	if length <= 0 {
-		return nil, fmt.Errorf("invalid packet length: %d", length)
-	}
-
-	packet := make([]byte, length)
-	if _, err := io.ReadFull(r, packet); err != nil {
-		return nil, err
-	}
-
-	return packet, nil
+	return readFrame(r, MaxPacketLength)
 }
 
 func ReadPacketID(packet []byte) (int32, []byte, error) {
@@ -49,119 +23,131 @@ func ReadPacketID(packet []byte) (int32, []byte, error) {
 	return id, packet[n:], nil
 }
 
-func ReadHandshakeNextState(packet []byte) (int32, error) {
-	id, payload, err := ReadPacketID(packet)
+func ReadLoginAcknowledged(packet []byte, protocolVersion int32) error {
+	id, _, err := ReadPacketID(packet)
 	if err != nil {
-		return 0, fmt.Errorf("read handshake id: %w", err)
+		return fmt.Errorf("read login acknowledged id: %w", err)
 	}
-	if id != 0x00 {
-		return 0, fmt.Errorf("unexpected handshake packet id: %d", id)
+	if expected := VersionFor(protocolVersion).Login.LoginAcknowledged; id != expected {
+		return fmt.Errorf("unexpected login acknowledged packet id: %d", id)
 	}
 
-	_, n, err := decodeVarIntFromBytes(payload) // protocol version
-	if err != nil {
-		return 0, fmt.Errorf("read protocol version: %w", err)
-	}
-	payload = payload[n:]
+	return nil
+}
 
-	hostLen, n, err := decodeVarIntFromBytes(payload)
-	if err != nil {
-		return 0, fmt.Errorf("read host len: %w", err)
-	}
-	payload = payload[n:]
-	if hostLen < 0 || len(payload) < int(hostLen)+2 {
-		return 0, fmt.Errorf("invalid host field")
-	}
-	payload = payload[hostLen:]
+func SendLoginDisconnect(w io.Writer, message string) error {
+	return sendDisconnect(w, loginPacketIDs.Disconnect, message, false)
+}
 
-	if len(payload) < 2 {
-		return 0, fmt.Errorf("missing port")
+func SendLoginSuccess(w io.Writer, protocolVersion int32, uuid UUID, username string) error {
+	version := VersionFor(protocolVersion)
+
+	payload := make([]byte, 0, 1+16+len(username)+16)
+	payload = append(payload, EncodeVarInt(version.Login.LoginSuccess)...)
+	payload = append(payload, uuid[:]...)
+	payload = append(payload, EncodeVarInt(int32(len(username)))...)
+	payload = append(payload, []byte(username)...)
+	payload = append(payload, 0x00) // properties count
+	if version.LoginSuccessStrictErrorHandling {
+		payload = append(payload, 0x00) // strict error handling
 	}
-	_ = binary.BigEndian.Uint16(payload[:2])
-	payload = payload[2:]
 
-	nextState, _, err := decodeVarIntFromBytes(payload)
-	if err != nil {
-		return 0, fmt.Errorf("read next state: %w", err)
+	packetLen := EncodeVarInt(int32(len(payload)))
+	packet := append(packetLen, payload...)
+
+	_, err := w.Write(packet)
+	return err
+}
+
+func SendConfigurationDisconnect(w io.Writer, protocolVersion int32, message string) error {
+	version := VersionFor(protocolVersion)
+	if !version.HasConfigurationState {
+		return fmt.Errorf("protocol %d has no configuration state", protocolVersion)
 	}
 
-	return nextState, nil
+	return sendDisconnect(w, version.Configuration.Disconnect, message, version.NBTTextComponents)
 }
 
-func ReadLoginStartUsername(packet []byte) (string, error) {
+func SendPlayDisconnect(w io.Writer, protocolVersion int32, message string) error {
+	version := VersionFor(protocolVersion)
+	return sendDisconnect(w, version.Play.Disconnect, message, version.NBTTextComponents)
+}
+
+// SendPlayKeepAlive sends a Keep Alive the client must echo back with the
+// same id; vanilla clients are kicked after 15 seconds without one.
+func SendPlayKeepAlive(w io.Writer, protocolVersion int32, id int64) error {
+	version := VersionFor(protocolVersion)
+
+	payload := EncodeVarInt(version.Play.KeepAlive)
+	payload = binary.BigEndian.AppendUint64(payload, uint64(id))
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+// ReadPlayKeepAlive returns the id echoed by the client in a serverbound Keep
+// Alive.
+func ReadPlayKeepAlive(packet []byte, protocolVersion int32) (int64, error) {
 	id, payload, err := ReadPacketID(packet)
 	if err != nil {
-		return "", fmt.Errorf("read login start id: %w", err)
+		return 0, fmt.Errorf("read keep alive id: %w", err)
 	}
-	if id != 0x00 {
-		return "", fmt.Errorf("unexpected login start packet id: %d", id)
+	if expected := VersionFor(protocolVersion).Play.KeepAliveResponse; id != expected {
+		return 0, fmt.Errorf("unexpected keep alive packet id: %d", id)
 	}
 
-	usernameLen, n, err := decodeVarIntFromBytes(payload)
+	reader := payloadReader{data: payload}
+	keepAliveID, err := reader.int64()
 	if err != nil {
-		return "", fmt.Errorf("read username length: %w", err)
-	}
-	payload = payload[n:]
-	if usernameLen <= 0 || len(payload) < int(usernameLen) {
-		return "", fmt.Errorf("invalid username length")
+		return 0, fmt.Errorf("read keep alive payload: %w", err)
 	}
 
-	return string(payload[:usernameLen]), nil
+	return keepAliveID, nil
 }
 
-func SendLoginDisconnect(w io.Writer, message string) error {
-	reasonPayload, err := loginDisconnectReasonPayload(message)
-	if err != nil {
-		return err
+// SendConfigurationTransfer tells a 1.20.5+ client in the configuration state
+// to reconnect to host:port. The client sends a handshake with next state
+// StateTransfer to the new server.
+func SendConfigurationTransfer(w io.Writer, protocolVersion int32, host string, port int32) error {
+	version := VersionFor(protocolVersion)
+	if !version.SupportsTransfer {
+		return fmt.Errorf("protocol %d does not support transfers", protocolVersion)
 	}
 
-	reason := string(reasonPayload)
-
-	payload := make([]byte, 0, 1+len(reason)+5)
-	payload = append(payload, 0x00) // Login Disconnect packet id
-	payload = append(payload, EncodeVarInt(int32(len(reason)))...)
-	payload = append(payload, []byte(reason)...)
-
-	packetLen := EncodeVarInt(int32(len(payload)))
-	packet := append(packetLen, payload...)
-
-	_, err = w.Write(packet)
-	return err
+	return sendTransfer(w, version.Configuration.Transfer, host, port)
 }
 
-func SendLoginSuccess(w io.Writer, username string) error {
-	payload := make([]byte, 0, 1+16+len(username)+16)
-	payload = append(payload, 0x02) // Login Success packet id
-
-	uuid := make([]byte, 16)
-	if _, err := rand.Read(uuid); err != nil {
-		return fmt.Errorf("generate uuid: %w", err)
+func SendPlayTransfer(w io.Writer, protocolVersion int32, host string, port int32) error {
+	version := VersionFor(protocolVersion)
+	if !version.SupportsTransfer {
+		return fmt.Errorf("protocol %d does not support transfers", protocolVersion)
 	}
-	payload = append(payload, uuid...)
-	payload = append(payload, EncodeVarInt(int32(len(username)))...)
-	payload = append(payload, []byte(username)...)
-	payload = append(payload, 0x00) // properties count
 
-	packetLen := EncodeVarInt(int32(len(payload)))
-	packet := append(packetLen, payload...)
+	return sendTransfer(w, version.Play.Transfer, host, port)
+}
 
-	_, err := w.Write(packet)
+func sendTransfer(w io.Writer, packetID int32, host string, port int32) error {
+	payload := make([]byte, 0, 1+5+len(host)+5)
+	payload = append(payload, EncodeVarInt(packetID)...)
+	payload = append(payload, EncodeVarInt(int32(len(host)))...)
+	payload = append(payload, []byte(host)...)
+	payload = append(payload, EncodeVarInt(port)...)
+
+	_, err := w.Write(WrapPacket(payload))
 	return err
 }
 
-func SendPlayDisconnect(w io.Writer, message string) error {
-	reasonPayload, err := loginDisconnectReasonPayload(message)
+// sendDisconnect writes a disconnect packet whose reason is either a JSON
+// string or, when nbt is set, a network NBT text component.
+func sendDisconnect(w io.Writer, packetID int32, message string, nbt bool) error {
+	payload := make([]byte, 0, 1+len(message)+5)
+	payload = append(payload, EncodeVarInt(packetID)...)
+
+	payload, err := appendTextComponent(payload, message, nbt)
 	if err != nil {
 		return err
 	}
 
-	reason := string(reasonPayload)
-
-	payload := make([]byte, 0, 1+len(reason)+5)
-	payload = append(payload, 0x1A) // Play Disconnect packet id (1.20/1.20.1)
-	payload = append(payload, EncodeVarInt(int32(len(reason)))...)
-	payload = append(payload, []byte(reason)...)
-
 	packetLen := EncodeVarInt(int32(len(payload)))
 	packet := append(packetLen, payload...)
 
@@ -169,52 +155,42 @@ func SendPlayDisconnect(w io.Writer, message string) error {
 	return err
 }
 
-func loginDisconnectReasonPayload(message string) ([]byte, error) {
-	trimmed := strings.TrimSpace(message)
-	if trimmed != "" && json.Valid([]byte(trimmed)) {
-		return []byte(trimmed), nil
+// appendTextComponent appends message as a length-prefixed JSON string or,
+// when nbt is set, as a network NBT text component.
+func appendTextComponent(dst []byte, message string, nbt bool) ([]byte, error) {
+	if nbt {
+		component, err := TextComponentNBT(message)
+		if err != nil {
+			return nil, err
+		}
+		return append(dst, component...), nil
 	}
 
-	type disconnectReason struct {
-		Text string `json:"text"`
+	component, err := textComponentPayload(message)
+	if err != nil {
+		return nil, err
 	}
-
-	return json.Marshal(disconnectReason{Text: message})
+	dst = append(dst, EncodeVarInt(int32(len(component)))...)
+	return append(dst, component...), nil
 }
 
-func SendStatusResponse(w io.Writer, version string, protocolVersion int32, motd string, maxPlayers int32, onlinePlayers int32) error {
-	status := StatusResponse{}
-	status.Version.Name = version
-	status.Version.Protocol = protocolVersion
-	status.Players.Max = maxPlayers
-	status.Players.Online = onlinePlayers
-	status.Description.Text = motd
-
-	response, err := json.Marshal(status)
-	if err != nil {
-		return err
+// textComponentPayload passes JSON text components (objects, arrays and
+// strings) through unchanged and converts anything else from § formatted text.
+func textComponentPayload(message string) ([]byte, error) {
+	trimmed := strings.TrimSpace(message)
+	if isJSONTextComponent(trimmed) {
+		return []byte(trimmed), nil
 	}
 
-	payload := make([]byte, 0, 1+len(response)+5)
-	payload = append(payload, 0x00)
-	payload = append(payload, EncodeVarInt(int32(len(response)))...)
-	payload = append(payload, response...)
-
-	packetLen := EncodeVarInt(int32(len(payload)))
-	packet := append(packetLen, payload...)
-
-	_, err = w.Write(packet)
-	return err
+	return json.Marshal(ParseLegacyText(message))
 }
 
-func SendPong(w io.Writer, pingPayload []byte) error {
-	payload := make([]byte, 0, 1+len(pingPayload))
-	payload = append(payload, 0x01)
-	payload = append(payload, pingPayload...)
+func isJSONTextComponent(value string) bool {
+	if value == "" || !strings.ContainsRune("{[\"", rune(value[0])) {
+		return false
+	}
 
-	packet := append(EncodeVarInt(int32(len(payload))), payload...)
-	_, err := w.Write(packet)
-	return err
+	return json.Valid([]byte(value))
 }
 
 func WrapPacket(payload []byte) []byte {
@@ -0,0 +1,90 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// payloadReader decodes fields from a packet payload that has already been
+// read off the wire, reporting truncated fields as io.ErrUnexpectedEOF.
+type payloadReader struct {
+	data []byte
+}
+
+func (r *payloadReader) varInt() (int32, error) {
+	value, n, err := decodeVarIntFromBytes(r.data)
+	if err != nil {
+		return 0, err
+	}
+	r.data = r.data[n:]
+
+	return value, nil
+}
+
+func (r *payloadReader) bytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("negative length: %d", n)
+	}
+	if len(r.data) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value, nil
+}
+
+func (r *payloadReader) prefixedBytes(maxLen int) ([]byte, error) {
+	length, err := r.varInt()
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 || int(length) > maxLen {
+		return nil, fmt.Errorf("invalid length: %d", length)
+	}
+
+	return r.bytes(int(length))
+}
+
+func (r *payloadReader) string(maxLen int) (string, error) {
+	value, err := r.prefixedBytes(maxLen)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+func (r *payloadReader) bool() (bool, error) {
+	value, err := r.bytes(1)
+	if err != nil {
+		return false, err
+	}
+
+	return value[0] != 0x00, nil
+}
+
+func (r *payloadReader) int64() (int64, error) {
+	value, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(binary.BigEndian.Uint64(value)), nil
+}
+
+func (r *payloadReader) uuid() (UUID, error) {
+	value, err := r.bytes(16)
+	if err != nil {
+		return UUID{}, err
+	}
+
+	var uuid UUID
+	copy(uuid[:], value)
+	return uuid, nil
+}
+
+func (r *payloadReader) remaining() []byte {
+	return r.data
+}
@@ -0,0 +1,267 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// GameModeSpectator lets a limbo player fly through the void without falling
+// and without waiting for chunks.
+const GameModeSpectator byte = 3
+
+// GameEventStartWaitingForChunks tells 1.20.3+ clients that the level is
+// ready; they keep the loading screen up until it arrives.
+const GameEventStartWaitingForChunks byte = 13
+
+const (
+	joinGameViewDistance = 2
+	joinGameSeaLevel     = 63
+)
+
+// SendJoinGame sends the play Login packet that places the player in
+// VoidDimension. Before 1.20.2 it also carries the registries the client
+// would otherwise receive during configuration.
+func SendJoinGame(w io.Writer, protocolVersion int32, entityID int32, gameMode byte) error {
+	version := VersionFor(protocolVersion)
+
+	payload := EncodeVarInt(version.Play.Login)
+	payload = binary.BigEndian.AppendUint32(payload, uint32(entityID))
+	payload = append(payload, 0x00) // hardcore
+
+	if !version.HasConfigurationState {
+		payload = append(payload, gameMode, 0xFF) // previous game mode: none
+		payload = append(payload, EncodeVarInt(1)...)
+		payload = appendString(payload, VoidDimension)
+
+		var err error
+		payload, err = appendNamedNBT(payload, registryCodec(voidWorldRegistries(version.Protocol)))
+		if err != nil {
+			return fmt.Errorf("encode registry codec: %w", err)
+		}
+
+		payload = appendString(payload, VoidDimension)      // dimension type
+		payload = appendString(payload, VoidDimension)      // dimension name
+		payload = binary.BigEndian.AppendUint64(payload, 0) // hashed seed
+		payload = append(payload, EncodeVarInt(1)...)       // max players
+		payload = append(payload, EncodeVarInt(joinGameViewDistance)...)
+		payload = append(payload, EncodeVarInt(joinGameViewDistance)...) // simulation distance
+		payload = append(payload,
+			0x00, // reduced debug info
+			0x01, // enable respawn screen
+			0x00, // debug world
+			0x01, // flat world
+			0x00, // has death location
+		)
+		if version.Protocol >= protocol1_20_1 {
+			payload = append(payload, EncodeVarInt(0)...) // portal cooldown
+		}
+
+		_, err = w.Write(WrapPacket(payload))
+		return err
+	}
+
+	payload = append(payload, EncodeVarInt(1)...)
+	payload = appendString(payload, VoidDimension)
+	payload = append(payload, EncodeVarInt(1)...) // max players
+	payload = append(payload, EncodeVarInt(joinGameViewDistance)...)
+	payload = append(payload, EncodeVarInt(joinGameViewDistance)...) // simulation distance
+	payload = append(payload,
+		0x00, // reduced debug info
+		0x01, // enable respawn screen
+		0x00, // limited crafting
+	)
+	if version.Protocol >= protocol1_20_6 {
+		payload = append(payload, EncodeVarInt(0)...) // dimension type registry id
+	} else {
+		payload = appendString(payload, VoidDimension)
+	}
+	payload = appendString(payload, VoidDimension)
+	payload = binary.BigEndian.AppendUint64(payload, 0) // hashed seed
+	payload = append(payload, gameMode, 0xFF)           // previous game mode: none
+	payload = append(payload,
+		0x00, // debug world
+		0x01, // flat world
+		0x00, // has death location
+	)
+	payload = append(payload, EncodeVarInt(0)...) // portal cooldown
+	if version.Protocol >= protocol1_21_3 {
+		payload = append(payload, EncodeVarInt(joinGameSeaLevel)...)
+	}
+	if version.Protocol >= protocol1_20_6 {
+		payload = append(payload, 0x00) // enforces secure chat
+	}
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendSetDefaultSpawnPosition sets where the compass points and where the
+// client respawns.
+func SendSetDefaultSpawnPosition(w io.Writer, protocolVersion int32, x, y, z int32, angle float32) error {
+	version := VersionFor(protocolVersion)
+
+	payload := EncodeVarInt(version.Play.SetDefaultSpawnPosition)
+	payload = binary.BigEndian.AppendUint64(payload, encodePosition(x, y, z))
+	payload = binary.BigEndian.AppendUint32(payload, math.Float32bits(angle))
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendSyncPlayerPosition teleports the player to an absolute position. Before
+// 1.20.3 this is also what closes the client's loading screen.
+func SendSyncPlayerPosition(w io.Writer, protocolVersion int32, x, y, z float64, yaw, pitch float32, teleportID int32) error {
+	version := VersionFor(protocolVersion)
+
+	payload := EncodeVarInt(version.Play.SyncPlayerPosition)
+	if version.Protocol >= protocol1_21_3 {
+		payload = append(payload, EncodeVarInt(teleportID)...)
+	}
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(x))
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(y))
+	payload = binary.BigEndian.AppendUint64(payload, math.Float64bits(z))
+	if version.Protocol >= protocol1_21_3 {
+		payload = append(payload, make([]byte, 3*8)...) // velocity
+	}
+	payload = binary.BigEndian.AppendUint32(payload, math.Float32bits(yaw))
+	payload = binary.BigEndian.AppendUint32(payload, math.Float32bits(pitch))
+	if version.Protocol >= protocol1_21_3 {
+		payload = binary.BigEndian.AppendUint32(payload, 0) // relative flags
+	} else {
+		payload = append(payload, 0x00) // relative flags
+		payload = append(payload, EncodeVarInt(teleportID)...)
+	}
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+func SendGameEvent(w io.Writer, protocolVersion int32, event byte, value float32) error {
+	version := VersionFor(protocolVersion)
+
+	payload := EncodeVarInt(version.Play.GameEvent)
+	payload = append(payload, event)
+	payload = binary.BigEndian.AppendUint32(payload, math.Float32bits(value))
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendSystemChat shows message in chat or, when overlay is set, above the
+// hotbar as an action bar message.
+func SendSystemChat(w io.Writer, protocolVersion int32, message string, overlay bool) error {
+	version := VersionFor(protocolVersion)
+
+	payload := EncodeVarInt(version.Play.SystemChat)
+	payload, err := appendTextComponent(payload, message, version.NBTTextComponents)
+	if err != nil {
+		return err
+	}
+	if overlay {
+		payload = append(payload, 0x01)
+	} else {
+		payload = append(payload, 0x00)
+	}
+
+	_, err = w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendTitle shows title and subtitle for stayTicks, fading in and out over
+// fadeInTicks and fadeOutTicks. An empty subtitle is not sent.
+func SendTitle(w io.Writer, protocolVersion int32, title, subtitle string, fadeInTicks, stayTicks, fadeOutTicks int32) error {
+	version := VersionFor(protocolVersion)
+
+	times := EncodeVarInt(version.Play.SetTitleAnimationTimes)
+	times = binary.BigEndian.AppendUint32(times, uint32(fadeInTicks))
+	times = binary.BigEndian.AppendUint32(times, uint32(stayTicks))
+	times = binary.BigEndian.AppendUint32(times, uint32(fadeOutTicks))
+	if _, err := w.Write(WrapPacket(times)); err != nil {
+		return err
+	}
+
+	if subtitle != "" {
+		payload, err := appendTextComponent(EncodeVarInt(version.Play.SetSubtitleText), subtitle, version.NBTTextComponents)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(WrapPacket(payload)); err != nil {
+			return err
+		}
+	}
+
+	// The title goes last: it is what makes the client show both texts.
+	payload, err := appendTextComponent(EncodeVarInt(version.Play.SetTitleText), title, version.NBTTextComponents)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(WrapPacket(payload))
+	return err
+}
+
+const BossBarColorYellow int32 = 4
+
+const (
+	bossBarActionAdd          int32 = 0
+	bossBarActionRemove       int32 = 1
+	bossBarActionUpdateHealth int32 = 2
+	bossBarActionUpdateTitle  int32 = 3
+)
+
+// SendBossBarAdd shows a boss bar identified by id with progress between 0
+// and 1.
+func SendBossBarAdd(w io.Writer, protocolVersion int32, id UUID, title string, progress float32, color int32) error {
+	version := VersionFor(protocolVersion)
+
+	payload := bossBarHeader(version, id, bossBarActionAdd)
+	payload, err := appendTextComponent(payload, title, version.NBTTextComponents)
+	if err != nil {
+		return err
+	}
+	payload = binary.BigEndian.AppendUint32(payload, math.Float32bits(progress))
+	payload = append(payload, EncodeVarInt(color)...)
+	payload = append(payload, EncodeVarInt(0)...) // no notches
+	payload = append(payload, 0x00)               // flags
+
+	_, err = w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendBossBarUpdate changes the title and progress of a boss bar shown with
+// SendBossBarAdd.
+func SendBossBarUpdate(w io.Writer, protocolVersion int32, id UUID, title string, progress float32) error {
+	version := VersionFor(protocolVersion)
+
+	health := bossBarHeader(version, id, bossBarActionUpdateHealth)
+	health = binary.BigEndian.AppendUint32(health, math.Float32bits(progress))
+	if _, err := w.Write(WrapPacket(health)); err != nil {
+		return err
+	}
+
+	payload, err := appendTextComponent(bossBarHeader(version, id, bossBarActionUpdateTitle), title, version.NBTTextComponents)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendBossBarRemove hides a boss bar shown with SendBossBarAdd.
+func SendBossBarRemove(w io.Writer, protocolVersion int32, id UUID) error {
+	_, err := w.Write(WrapPacket(bossBarHeader(VersionFor(protocolVersion), id, bossBarActionRemove)))
+	return err
+}
+
+func bossBarHeader(version Version, id UUID, action int32) []byte {
+	payload := EncodeVarInt(version.Play.BossBar)
+	payload = append(payload, id[:]...)
+	return append(payload, EncodeVarInt(action)...)
+}
+
+func encodePosition(x, y, z int32) uint64 {
+	return uint64(x&0x3FFFFFF)<<38 | uint64(z&0x3FFFFFF)<<12 | uint64(y&0xFFF)
+}
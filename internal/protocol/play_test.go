@@ -0,0 +1,293 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestSendJoinGame_VersionLayouts(t *testing.T) {
+	tests := []struct {
+		protocolVersion int32
+		packetID        int32
+		// tail is everything after the hashed seed.
+		tail []byte
+	}{
+		{protocolVersion: 764, packetID: 0x29, tail: []byte{GameModeSpectator, 0xFF, 0x00, 0x01, 0x00, 0x00}},
+		{protocolVersion: 766, packetID: 0x2B, tail: []byte{GameModeSpectator, 0xFF, 0x00, 0x01, 0x00, 0x00, 0x00}},
+		{protocolVersion: 769, packetID: 0x2C, tail: []byte{GameModeSpectator, 0xFF, 0x00, 0x01, 0x00, 0x00, 63, 0x00}},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := SendJoinGame(&out, tt.protocolVersion, 7, GameModeSpectator); err != nil {
+			t.Fatalf("SendJoinGame(%d) failed: %v", tt.protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		id, payload, err := ReadPacketID(packet)
+		if err != nil {
+			t.Fatalf("ReadPacketID failed: %v", err)
+		}
+		if id != tt.packetID {
+			t.Fatalf("protocol %d: expected packet id 0x%02X, got 0x%02X", tt.protocolVersion, tt.packetID, id)
+		}
+
+		reader := payloadReader{data: payload}
+		entityID, _ := reader.bytes(4)
+		if binary.BigEndian.Uint32(entityID) != 7 {
+			t.Fatalf("protocol %d: unexpected entity id %v", tt.protocolVersion, entityID)
+		}
+		hardcore, _ := reader.bool()
+		dimensionCount, _ := reader.varInt()
+		dimensionName, _ := reader.string(64)
+		if hardcore || dimensionCount != 1 || dimensionName != VoidDimension {
+			t.Fatalf("protocol %d: unexpected world header", tt.protocolVersion)
+		}
+		// max players, view distance, simulation distance, reduced debug info,
+		// respawn screen and limited crafting.
+		if header, _ := reader.bytes(6); !bytes.Equal(header, []byte{1, joinGameViewDistance, joinGameViewDistance, 0, 1, 0}) {
+			t.Fatalf("protocol %d: unexpected settings %v", tt.protocolVersion, header)
+		}
+		if tt.protocolVersion >= protocol1_20_6 {
+			if dimensionType, _ := reader.varInt(); dimensionType != 0 {
+				t.Fatalf("protocol %d: expected dimension type id 0, got %d", tt.protocolVersion, dimensionType)
+			}
+		} else if dimensionType, _ := reader.string(64); dimensionType != VoidDimension {
+			t.Fatalf("protocol %d: unexpected dimension type %q", tt.protocolVersion, dimensionType)
+		}
+		if name, _ := reader.string(64); name != VoidDimension {
+			t.Fatalf("protocol %d: unexpected dimension name %q", tt.protocolVersion, name)
+		}
+		if _, err := reader.int64(); err != nil {
+			t.Fatalf("protocol %d: read hashed seed: %v", tt.protocolVersion, err)
+		}
+		if tail := reader.remaining(); !bytes.Equal(tail, tt.tail) {
+			t.Fatalf("protocol %d: expected tail %v, got %v", tt.protocolVersion, tt.tail, tail)
+		}
+	}
+}
+
+func TestSendJoinGame_CarriesRegistryCodecBefore1_20_2(t *testing.T) {
+	for _, protocolVersion := range []int32{762, 763} {
+		var out bytes.Buffer
+		if err := SendJoinGame(&out, protocolVersion, 1, GameModeSpectator); err != nil {
+			t.Fatalf("SendJoinGame(%d) failed: %v", protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		id, payload, _ := ReadPacketID(packet)
+		if id != 0x28 {
+			t.Fatalf("expected packet id 0x28, got 0x%02X", id)
+		}
+
+		// entity id, hardcore, game mode, previous game mode, one dimension name,
+		// then the registry codec as named NBT with an empty root name.
+		codecStart := 4 + 3 + 1 + 1 + len(VoidDimension)
+		if !bytes.Equal(payload[codecStart:codecStart+3], []byte{nbtTagCompound, 0x00, 0x00}) {
+			t.Fatalf("protocol %d: expected named root compound, got %v", protocolVersion, payload[codecStart:codecStart+3])
+		}
+
+		wantTail := []byte{0x00, 0x01, 0x00, 0x01, 0x00}
+		if protocolVersion >= protocol1_20_1 {
+			wantTail = append(wantTail, 0x00) // portal cooldown
+		}
+		if !bytes.HasSuffix(payload, wantTail) {
+			t.Fatalf("protocol %d: unexpected tail %v", protocolVersion, payload[len(payload)-len(wantTail):])
+		}
+	}
+}
+
+func TestSendRegistryData_PacketPerRegistryFrom1_20_5(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendRegistryData(&out, 767); err != nil {
+		t.Fatalf("SendRegistryData failed: %v", err)
+	}
+
+	var keys []string
+	for out.Len() > 0 {
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		id, payload, _ := ReadPacketID(packet)
+		if id != 0x07 {
+			t.Fatalf("expected registry data id 0x07, got 0x%02X", id)
+		}
+
+		reader := payloadReader{data: payload}
+		key, err := reader.string(64)
+		if err != nil {
+			t.Fatalf("read registry key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) != len(voidWorldRegistries(767)) || keys[0] != "minecraft:dimension_type" {
+		t.Fatalf("unexpected registries: %v", keys)
+	}
+}
+
+func TestSendRegistryData_SingleCodecBefore1_20_5(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendRegistryData(&out, 765); err != nil {
+		t.Fatalf("SendRegistryData failed: %v", err)
+	}
+
+	packet, err := ReadPacket(&out)
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected a single packet, %d bytes left", out.Len())
+	}
+	id, payload, _ := ReadPacketID(packet)
+	if id != 0x05 {
+		t.Fatalf("expected registry data id 0x05, got 0x%02X", id)
+	}
+	// Network NBT has no root name, so the first child tag follows directly.
+	if payload[0] != nbtTagCompound || payload[1] != nbtTagCompound {
+		t.Fatalf("expected unnamed root compound, got %v", payload[:4])
+	}
+
+	if err := SendRegistryData(&out, 763); err == nil {
+		t.Fatal("expected registry data to be rejected before 1.20.2")
+	}
+}
+
+func TestSendSyncPlayerPosition_Layouts(t *testing.T) {
+	tests := []struct {
+		protocolVersion int32
+		packetID        int32
+		length          int
+	}{
+		{protocolVersion: 763, packetID: 0x3C, length: 3*8 + 2*4 + 1 + 1},
+		{protocolVersion: 766, packetID: 0x40, length: 3*8 + 2*4 + 1 + 1},
+		{protocolVersion: 768, packetID: 0x42, length: 1 + 6*8 + 2*4 + 4},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := SendSyncPlayerPosition(&out, tt.protocolVersion, 0.5, 300, 0.5, 0, 0, 1); err != nil {
+			t.Fatalf("SendSyncPlayerPosition failed: %v", err)
+		}
+
+		packet, _ := ReadPacket(&out)
+		id, payload, _ := ReadPacketID(packet)
+		if id != tt.packetID || len(payload) != tt.length {
+			t.Fatalf("protocol %d: expected id 0x%02X with %d bytes, got 0x%02X with %d", tt.protocolVersion, tt.packetID, tt.length, id, len(payload))
+		}
+
+		yOffset := 8
+		if tt.protocolVersion >= protocol1_21_3 {
+			yOffset = 1 + 8
+		}
+		if y := math.Float64frombits(binary.BigEndian.Uint64(payload[yOffset:])); y != 300 {
+			t.Fatalf("protocol %d: expected y 300, got %v", tt.protocolVersion, y)
+		}
+	}
+}
+
+func TestSendSystemChat_Overlay(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendSystemChat(&out, 763, "hi", true); err != nil {
+		t.Fatalf("SendSystemChat failed: %v", err)
+	}
+
+	packet, _ := ReadPacket(&out)
+	expected := append([]byte{0x64, 0x0D}, []byte(`{"text":"hi"}`)...)
+	expected = append(expected, 0x01)
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("expected %q, got %q", expected, packet)
+	}
+}
+
+func TestSendTitle_SendsTitleLast(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendTitle(&out, 769, "Maintenance", "back soon", 10, 70, 20); err != nil {
+		t.Fatalf("SendTitle failed: %v", err)
+	}
+
+	var ids []int32
+	for out.Len() > 0 {
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		id, _, _ := ReadPacketID(packet)
+		ids = append(ids, id)
+	}
+
+	expected := []int32{0x6D, 0x6A, 0x6C}
+	if len(ids) != len(expected) {
+		t.Fatalf("expected packets %v, got %v", expected, ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected packets %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestEncodePosition(t *testing.T) {
+	if got := encodePosition(0, 300, 0); got != 300 {
+		t.Fatalf("expected 300, got %d", got)
+	}
+	if got := encodePosition(-1, -1, -1); got != math.MaxUint64 {
+		t.Fatalf("expected all bits set, got %x", got)
+	}
+	if got := encodePosition(1, 0, 2); got != 1<<38|2<<12 {
+		t.Fatalf("unexpected position %x", got)
+	}
+}
+
+func TestSendBossBar(t *testing.T) {
+	id := OfflinePlayerUUID("bar")
+
+	var out bytes.Buffer
+	if err := SendBossBarAdd(&out, 763, id, "hi", 0.5, BossBarColorYellow); err != nil {
+		t.Fatalf("SendBossBarAdd failed: %v", err)
+	}
+	if err := SendBossBarUpdate(&out, 769, id, "hi", 1); err != nil {
+		t.Fatalf("SendBossBarUpdate failed: %v", err)
+	}
+
+	packet, _ := ReadPacket(&out)
+	expected := append([]byte{0x0B}, id[:]...)
+	expected = append(expected, 0x00, 0x0D)
+	expected = append(expected, []byte(`{"text":"hi"}`)...)
+	expected = append(expected, 0x3F, 0x00, 0x00, 0x00, byte(BossBarColorYellow), 0x00, 0x00)
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("expected add packet %v, got %v", expected, packet)
+	}
+
+	packet, _ = ReadPacket(&out)
+	expected = append([]byte{0x0A}, id[:]...)
+	expected = append(expected, 0x02, 0x3F, 0x80, 0x00, 0x00)
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("expected health packet %v, got %v", expected, packet)
+	}
+
+	packet, _ = ReadPacket(&out)
+	packetID, payload, _ := ReadPacketID(packet)
+	if packetID != 0x0A || payload[16] != 0x03 || payload[17] != nbtTagCompound {
+		t.Fatalf("expected NBT title update, got %v", packet)
+	}
+
+	if err := SendBossBarRemove(&out, 763, id); err != nil {
+		t.Fatalf("SendBossBarRemove failed: %v", err)
+	}
+	packet, _ = ReadPacket(&out)
+	expected = append([]byte{0x0B}, id[:]...)
+	expected = append(expected, 0x01)
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("expected remove packet %v, got %v", expected, packet)
+	}
+}
@@ -0,0 +1,66 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+)
+
+const maxLoginPluginPayloadSize = 1 << 20
+
+// LoginPluginRequest is a custom query sent during login, used by proxies such
+// as Velocity and by mod loaders to negotiate before Login Success.
+type LoginPluginRequest struct {
+	MessageID int32
+	Channel   string
+	Data      []byte
+}
+
+type LoginPluginResponse struct {
+	MessageID int32
+	// Understood is false when the client has no handler for the channel;
+	// Data is empty in that case.
+	Understood bool
+	Data       []byte
+}
+
+func SendLoginPluginRequest(w io.Writer, request LoginPluginRequest) error {
+	payload := make([]byte, 0, 1+5+len(request.Channel)+5+len(request.Data))
+	payload = append(payload, EncodeVarInt(loginPacketIDs.PluginRequest)...)
+	payload = append(payload, EncodeVarInt(request.MessageID)...)
+	payload = append(payload, EncodeVarInt(int32(len(request.Channel)))...)
+	payload = append(payload, []byte(request.Channel)...)
+	payload = append(payload, request.Data...)
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+func ReadLoginPluginResponse(packet []byte) (LoginPluginResponse, error) {
+	id, payload, err := ReadPacketID(packet)
+	if err != nil {
+		return LoginPluginResponse{}, fmt.Errorf("read login plugin response id: %w", err)
+	}
+	if id != loginPacketIDs.PluginResponse {
+		return LoginPluginResponse{}, fmt.Errorf("unexpected login plugin response packet id: %d", id)
+	}
+
+	reader := payloadReader{data: payload}
+
+	var response LoginPluginResponse
+	if response.MessageID, err = reader.varInt(); err != nil {
+		return LoginPluginResponse{}, fmt.Errorf("read message id: %w", err)
+	}
+	if response.Understood, err = reader.bool(); err != nil {
+		return LoginPluginResponse{}, fmt.Errorf("read successful flag: %w", err)
+	}
+
+	data := reader.remaining()
+	if len(data) > maxLoginPluginPayloadSize {
+		return LoginPluginResponse{}, fmt.Errorf("login plugin response too long: %d", len(data))
+	}
+	if response.Understood {
+		response.Data = data
+	}
+
+	return response, nil
+}
@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSendLoginPluginRequest(t *testing.T) {
+	var out bytes.Buffer
+	request := LoginPluginRequest{MessageID: 300, Channel: "velocity:player_info", Data: []byte{0x04}}
+	if err := SendLoginPluginRequest(&out, request); err != nil {
+		t.Fatalf("SendLoginPluginRequest failed: %v", err)
+	}
+
+	packet, err := ReadPacket(&out)
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+
+	expected := []byte{0x04, 0xAC, 0x02, byte(len(request.Channel))}
+	expected = append(expected, request.Channel...)
+	expected = append(expected, 0x04)
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("unexpected login plugin request: %v", packet)
+	}
+}
+
+func TestReadLoginPluginResponse(t *testing.T) {
+	response, err := ReadLoginPluginResponse([]byte{0x02, 0x05, 0x01, 0xCA, 0xFE})
+	if err != nil {
+		t.Fatalf("ReadLoginPluginResponse failed: %v", err)
+	}
+	if response.MessageID != 5 || !response.Understood || !bytes.Equal(response.Data, []byte{0xCA, 0xFE}) {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+
+	response, err = ReadLoginPluginResponse([]byte{0x02, 0x06, 0x00})
+	if err != nil {
+		t.Fatalf("ReadLoginPluginResponse failed: %v", err)
+	}
+	if response.Understood || response.Data != nil {
+		t.Fatalf("expected not understood response without data, got %+v", response)
+	}
+
+	if _, err := ReadLoginPluginResponse([]byte{0x03, 0x00, 0x00}); err == nil {
+		t.Fatal("expected error for wrong packet id")
+	}
+}
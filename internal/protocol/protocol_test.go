@@ -94,24 +94,27 @@ func TestSendLoginDisconnect_AllowsRawJSONComponent(t *testing.T) {
 	}
 }
 
-func TestReadLoginStartUsername(t *testing.T) {
+func TestReadLoginStart(t *testing.T) {
 	payload := make([]byte, 0)
 	payload = append(payload, EncodeVarInt(0x00)...)
 	payload = append(payload, EncodeVarInt(int32(len("Steve")))...)
 	payload = append(payload, []byte("Steve")...)
 
-	username, err := ReadLoginStartUsername(payload)
+	loginStart, err := ReadLoginStart(payload, 758)
 	if err != nil {
-		t.Fatalf("ReadLoginStartUsername failed: %v", err)
+		t.Fatalf("ReadLoginStart failed: %v", err)
 	}
-	if username != "Steve" {
-		t.Fatalf("unexpected username: %s", username)
+	if loginStart.Username != "Steve" {
+		t.Fatalf("unexpected username: %s", loginStart.Username)
+	}
+	if loginStart.HasUUID || loginStart.Signature != nil {
+		t.Fatalf("expected no uuid or signature, got %+v", loginStart)
 	}
 }
 
 func TestSendLoginSuccess(t *testing.T) {
 	var out bytes.Buffer
-	if err := SendLoginSuccess(&out, "Steve"); err != nil {
+	if err := SendLoginSuccess(&out, 763, OfflinePlayerUUID("Steve"), "Steve"); err != nil {
 		t.Fatalf("SendLoginSuccess failed: %v", err)
 	}
 
@@ -129,6 +132,9 @@ func TestSendLoginSuccess(t *testing.T) {
 	if len(payload) < 16 {
 		t.Fatalf("payload too short for uuid: %d", len(payload))
 	}
+	if expected := OfflinePlayerUUID("Steve"); !bytes.Equal(payload[:16], expected[:]) {
+		t.Fatalf("expected offline uuid %s, got %x", expected, payload[:16])
+	}
 	payload = payload[16:]
 
 	usernameLen, n, err := decodeVarIntFromBytes(payload)
@@ -149,7 +155,7 @@ func TestSendLoginSuccess(t *testing.T) {
 
 func TestSendPlayDisconnect(t *testing.T) {
 	var out bytes.Buffer
-	if err := SendPlayDisconnect(&out, "custom disconnect text"); err != nil {
+	if err := SendPlayDisconnect(&out, 763, "custom disconnect text"); err != nil {
 		t.Fatalf("SendPlayDisconnect failed: %v", err)
 	}
 
@@ -184,7 +190,40 @@ func TestSendPlayDisconnect(t *testing.T) {
 	}
 }
 
-func TestReadHandshakeNextState(t *testing.T) {
+func TestPlayKeepAlive(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendPlayKeepAlive(&out, 767, 1234567890123); err != nil {
+		t.Fatalf("SendPlayKeepAlive failed: %v", err)
+	}
+
+	packet, err := ReadPacket(&out)
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	expected := []byte{0x26, 0x00, 0x00, 0x01, 0x1F, 0x71, 0xFB, 0x04, 0xCB}
+	if !bytes.Equal(packet, expected) {
+		t.Fatalf("expected keep alive %v, got %v", expected, packet)
+	}
+
+	// The client echoes the payload back with the serverbound packet id.
+	response := append([]byte{0x18}, packet[1:]...)
+	id, err := ReadPlayKeepAlive(response, 767)
+	if err != nil {
+		t.Fatalf("ReadPlayKeepAlive failed: %v", err)
+	}
+	if id != 1234567890123 {
+		t.Fatalf("expected echoed id 1234567890123, got %d", id)
+	}
+
+	if _, err := ReadPlayKeepAlive(packet, 767); err == nil {
+		t.Fatal("expected clientbound keep alive id to be rejected")
+	}
+	if _, err := ReadPlayKeepAlive([]byte{0x18, 0x01}, 767); err == nil {
+		t.Fatal("expected truncated keep alive to be rejected")
+	}
+}
+
+func TestReadHandshake(t *testing.T) {
 	handshake := make([]byte, 0)
 	handshake = append(handshake, EncodeVarInt(0x00)...)
 	handshake = append(handshake, EncodeVarInt(760)...)
@@ -193,18 +232,92 @@ func TestReadHandshakeNextState(t *testing.T) {
 	handshake = append(handshake, 0x63, 0xDD) // 25565
 	handshake = append(handshake, EncodeVarInt(1)...)
 
-	nextState, err := ReadHandshakeNextState(handshake)
+	parsed, err := ReadHandshake(handshake)
 	if err != nil {
-		t.Fatalf("ReadHandshakeNextState failed: %v", err)
+		t.Fatalf("ReadHandshake failed: %v", err)
 	}
-	if nextState != 1 {
-		t.Fatalf("expected status state 1, got %d", nextState)
+	if parsed.NextState != 1 {
+		t.Fatalf("expected status state 1, got %d", parsed.NextState)
+	}
+	if parsed.ProtocolVersion != 760 {
+		t.Fatalf("expected protocol 760, got %d", parsed.ProtocolVersion)
+	}
+	if parsed.Host != "localhost" || parsed.Port != 25565 {
+		t.Fatalf("unexpected address: %s:%d", parsed.Host, parsed.Port)
+	}
+	if parsed.IsForge() || parsed.BungeeCord != nil {
+		t.Fatalf("expected plain handshake, got %+v", parsed)
+	}
+}
+
+func TestReadHandshake_SplitsForgeAndBungeeCordSuffixes(t *testing.T) {
+	rawHost := "play.example.com.\x00FML2\x00"
+	bungeeHost := "play.example.com\x00203.0.113.7\x00069a79f444e94726a5befca90e38aaf5\x00[]"
+
+	cases := []struct {
+		rawHost     string
+		forgeMarker string
+		bungeeCord  *BungeeCordForwarding
+	}{
+		{rawHost: rawHost, forgeMarker: "FML2"},
+		{
+			rawHost: bungeeHost,
+			bungeeCord: &BungeeCordForwarding{
+				ClientIP:   "203.0.113.7",
+				UUID:       "069a79f444e94726a5befca90e38aaf5",
+				Properties: "[]",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		handshake := make([]byte, 0)
+		handshake = append(handshake, EncodeVarInt(0x00)...)
+		handshake = append(handshake, EncodeVarInt(763)...)
+		handshake = append(handshake, EncodeVarInt(int32(len(tc.rawHost)))...)
+		handshake = append(handshake, []byte(tc.rawHost)...)
+		handshake = append(handshake, 0x63, 0xDD)
+		handshake = append(handshake, EncodeVarInt(2)...)
+
+		parsed, err := ReadHandshake(handshake)
+		if err != nil {
+			t.Fatalf("ReadHandshake failed: %v", err)
+		}
+		if parsed.Host != "play.example.com" {
+			t.Fatalf("unexpected host: %q", parsed.Host)
+		}
+		if parsed.RawHost != tc.rawHost {
+			t.Fatalf("expected raw host to be preserved, got %q", parsed.RawHost)
+		}
+		if parsed.ForgeMarker != tc.forgeMarker {
+			t.Fatalf("expected forge marker %q, got %q", tc.forgeMarker, parsed.ForgeMarker)
+		}
+		if tc.bungeeCord == nil {
+			if parsed.BungeeCord != nil {
+				t.Fatalf("unexpected BungeeCord forwarding: %+v", parsed.BungeeCord)
+			}
+			continue
+		}
+		if parsed.BungeeCord == nil || *parsed.BungeeCord != *tc.bungeeCord {
+			t.Fatalf("expected BungeeCord forwarding %+v, got %+v", tc.bungeeCord, parsed.BungeeCord)
+		}
 	}
 }
 
 func TestSendStatusResponse(t *testing.T) {
+	description, err := StatusDescription("MineMock")
+	if err != nil {
+		t.Fatalf("StatusDescription failed: %v", err)
+	}
+
+	response := StatusResponse{
+		Version:     StatusVersion{Name: "1.19.4", Protocol: 760},
+		Players:     StatusPlayers{Max: 20, Online: 5},
+		Description: description,
+	}
+
 	var out bytes.Buffer
-	if err := SendStatusResponse(&out, "1.19.4", 760, "MineMock", 20, 5); err != nil {
+	if err := SendStatusResponse(&out, response); err != nil {
 		t.Fatalf("SendStatusResponse failed: %v", err)
 	}
 
@@ -234,8 +347,12 @@ func TestSendStatusResponse(t *testing.T) {
 	if err := json.Unmarshal(payload, &status); err != nil {
 		t.Fatalf("json unmarshal failed: %v", err)
 	}
-	if status.Description.Text != "MineMock" {
-		t.Fatalf("unexpected motd: %s", status.Description.Text)
+	var motd map[string]string
+	if err := json.Unmarshal(status.Description, &motd); err != nil {
+		t.Fatalf("description unmarshal failed: %v", err)
+	}
+	if motd["text"] != "MineMock" {
+		t.Fatalf("unexpected motd: %s", motd["text"])
 	}
 	if status.Players.Max != 20 || status.Players.Online != 5 {
 		t.Fatalf("unexpected player stats: %+v", status.Players)
@@ -0,0 +1,120 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Maximum packet lengths, id included, accepted from clients in each state.
+const (
+	// MaxHandshakePacketLength leaves room for BungeeCord IP forwarding in the
+	// server address, which Spigot reads up to 32767 characters.
+	MaxHandshakePacketLength = 1 + 5 + 3 + 32767*3 + 2 + 5
+	// MaxStatusPacketLength fits Status Request and Ping Request.
+	MaxStatusPacketLength = 1 + 8
+	// MaxLoginPacketLength fits a Login Plugin Response with the largest
+	// payload vanilla accepts (1 MiB).
+	MaxLoginPacketLength = 1 + 5 + 1 + 1024*1024
+	// MaxPacketLength is the vanilla frame limit, the largest length a 3-byte
+	// VarInt holds. It applies to the configuration and play states.
+	MaxPacketLength = 1<<21 - 1
+)
+
+// packetAllocationChunk is how much of a packet is allocated before its bytes
+// arrive. Larger packets grow as they are read, so a client cannot make the
+// server allocate memory just by announcing a long packet.
+const packetAllocationChunk = 64 * 1024
+
+// PacketTooLargeError is returned when a client announces a packet, or the
+// uncompressed size of a compressed one, longer than the limit of the current
+// state. The packet itself is not read or inflated.
+type PacketTooLargeError struct {
+	Length    int32
+	MaxLength int32
+}
+
+func (e *PacketTooLargeError) Error() string {
+	return fmt.Sprintf("packet length %d exceeds maximum %d", e.Length, e.MaxLength)
+}
+
+// PacketReader reads length-prefixed packets from a stream, refusing frames
+// longer than its maximum and inflating them once compression is enabled.
+type PacketReader struct {
+	r         io.Reader
+	maxLength int32
+	threshold int32
+}
+
+func NewPacketReader(r io.Reader, maxLength int32) *PacketReader {
+	return &PacketReader{r: r, maxLength: maxLength, threshold: -1}
+}
+
+// SetMaxLength changes the limit for the following packets, e.g. when the
+// connection switches state.
+func (p *PacketReader) SetMaxLength(maxLength int32) {
+	p.maxLength = maxLength
+}
+
+// EnableCompression reads every following packet in the compressed format.
+func (p *PacketReader) EnableCompression(threshold int32) {
+	p.threshold = threshold
+}
+
+// Reset makes p read from r, keeping its limit and compression, e.g. once
+// the stream is encrypted.
+func (p *PacketReader) Reset(r io.Reader) {
+	p.r = r
+}
+
+// ReadPacket returns the next packet (id and payload), uncompressed.
+func (p *PacketReader) ReadPacket() ([]byte, error) {
+	frame, err := readFrame(p.r, p.maxLength)
+	if err != nil {
+		return nil, err
+	}
+	if p.threshold < 0 {
+		return frame, nil
+	}
+
+	return decompressFrame(frame, p.threshold, p.maxLength)
+}
+
+func readFrame(r io.Reader, maxLength int32) ([]byte, error) {
+	length, err := ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("invalid packet length: %d", length)
+	}
+	if length > maxLength {
+		return nil, &PacketTooLargeError{Length: length, MaxLength: maxLength}
+	}
+
+	return readFull(r, int(length))
+}
+
+// readFull reads exactly length bytes, allocating them as they arrive beyond
+// packetAllocationChunk.
+func readFull(r io.Reader, length int) ([]byte, error) {
+	if length <= packetAllocationChunk {
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	var data bytes.Buffer
+	data.Grow(packetAllocationChunk)
+	if _, err := io.CopyN(&data, r, int64(length)); err != nil {
+		if errors.Is(err, io.EOF) && data.Len() > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return data.Bytes(), nil
+}
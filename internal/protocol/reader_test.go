@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestPacketReader_RejectsOversizedFrame(t *testing.T) {
+	// A length prefix announcing 2 GiB with no data behind it.
+	framed := EncodeVarInt(1<<31 - 1)
+
+	_, err := NewPacketReader(bytes.NewReader(framed), MaxPacketLength).ReadPacket()
+	var tooLarge *PacketTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected PacketTooLargeError, got %v", err)
+	}
+	if tooLarge.Length != 1<<31-1 || tooLarge.MaxLength != MaxPacketLength {
+		t.Fatalf("unexpected error fields: %+v", tooLarge)
+	}
+
+	if _, err := ReadPacket(bytes.NewReader(framed)); !errors.As(err, &tooLarge) {
+		t.Fatalf("expected ReadPacket to apply MaxPacketLength, got %v", err)
+	}
+}
+
+func TestPacketReader_SetMaxLength(t *testing.T) {
+	ping := WrapPacket([]byte{0x01, 0, 0, 0, 0, 0, 0, 0, 0x2A})
+	var stream bytes.Buffer
+	stream.Write(ping)
+	stream.Write(ping)
+
+	reader := NewPacketReader(&stream, MaxStatusPacketLength)
+	packet, err := reader.ReadPacket()
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if !bytes.Equal(packet, ping[1:]) {
+		t.Fatalf("expected %v, got %v", ping[1:], packet)
+	}
+
+	reader.SetMaxLength(4)
+	var tooLarge *PacketTooLargeError
+	if _, err := reader.ReadPacket(); !errors.As(err, &tooLarge) {
+		t.Fatalf("expected PacketTooLargeError, got %v", err)
+	}
+}
+
+func TestPacketReader_ReadsLargePacketsAsTheyArrive(t *testing.T) {
+	packet := bytes.Repeat([]byte{0x07}, 3*packetAllocationChunk+1)
+
+	read, err := NewPacketReader(bytes.NewReader(WrapPacket(packet)), MaxPacketLength).ReadPacket()
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if !bytes.Equal(read, packet) {
+		t.Fatalf("large packet was not read back intact")
+	}
+
+	truncated := WrapPacket(packet)[:packetAllocationChunk*2]
+	if _, err := NewPacketReader(bytes.NewReader(truncated), MaxPacketLength).ReadPacket(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestPacketReader_Compression(t *testing.T) {
+	packet := append([]byte{0x1A}, bytes.Repeat([]byte("MineMock "), 64)...)
+	framed, err := WrapCompressedPacket(packet, 256)
+	if err != nil {
+		t.Fatalf("WrapCompressedPacket failed: %v", err)
+	}
+
+	reader := NewPacketReader(bytes.NewReader(framed), MaxPacketLength)
+	reader.EnableCompression(256)
+	read, err := reader.ReadPacket()
+	if err != nil {
+		t.Fatalf("ReadPacket failed: %v", err)
+	}
+	if !bytes.Equal(read, packet) {
+		t.Fatalf("expected %v, got %v", packet, read)
+	}
+}
+
+func TestPacketReader_RejectsOversizedUncompressedLength(t *testing.T) {
+	// A zlib bomb: a small frame that inflates past the login limit.
+	packet := make([]byte, MaxLoginPacketLength+1)
+	framed, err := WrapCompressedPacket(packet, 256)
+	if err != nil {
+		t.Fatalf("WrapCompressedPacket failed: %v", err)
+	}
+	if len(framed) > MaxLoginPacketLength {
+		t.Fatalf("expected a frame within the login limit, got %d bytes", len(framed))
+	}
+
+	reader := NewPacketReader(bytes.NewReader(framed), MaxLoginPacketLength)
+	reader.EnableCompression(256)
+	_, err = reader.ReadPacket()
+	var tooLarge *PacketTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected PacketTooLargeError, got %v", err)
+	}
+	if tooLarge.Length != MaxLoginPacketLength+1 || tooLarge.MaxLength != MaxLoginPacketLength {
+		t.Fatalf("unexpected error fields: %+v", tooLarge)
+	}
+}
@@ -0,0 +1,244 @@
+package protocol
+
+import (
+	"fmt"
+	"io"
+)
+
+// VoidDimension is the only dimension registered for limbo players: an
+// overworld-like level 256 blocks tall with no blocks in it.
+const VoidDimension = "minecraft:overworld"
+
+const (
+	voidDimensionMinY   = 0
+	voidDimensionHeight = 256
+	voidBiome           = "minecraft:plains"
+)
+
+// damageTypes lists every vanilla damage type from 1.19.4 to 1.21.4. Clients
+// look all of their own up while joining, so missing ones are fatal, while
+// types a client does not know are kept as data-driven entries.
+var damageTypes = []string{
+	"arrow", "bad_respawn_point", "cactus", "campfire", "cramming",
+	"dragon_breath", "drown", "dry_out", "ender_pearl", "explosion", "fall",
+	"falling_anvil", "falling_block", "falling_stalactite", "fireball",
+	"fireworks", "fly_into_wall", "freeze", "generic", "generic_kill",
+	"hot_floor", "in_fire", "in_wall", "indirect_magic", "lava",
+	"lightning_bolt", "mace_smash", "magic", "mob_attack",
+	"mob_attack_no_aggro", "mob_projectile", "on_fire", "out_of_world",
+	"outside_border", "player_attack", "player_explosion", "sonic_boom",
+	"spit", "stalagmite", "starve", "sting", "sweet_berry_bush", "thorns",
+	"thrown", "trident", "unattributed_fireball", "wind_charge", "wither",
+	"wither_skull",
+}
+
+type registry struct {
+	key     string
+	entries []registryEntry
+}
+
+type registryEntry struct {
+	name    string
+	element map[string]any
+}
+
+// voidWorldRegistries returns the synchronized registries a client of
+// protocolVersion needs to join VoidDimension. Every entry carries its full
+// data, so the exchange does not depend on the client's built-in data packs.
+func voidWorldRegistries(protocolVersion int32) []registry {
+	registries := []registry{
+		{key: "minecraft:dimension_type", entries: []registryEntry{{
+			name: VoidDimension,
+			element: map[string]any{
+				"piglin_safe":                     false,
+				"natural":                         false,
+				"ambient_light":                   float64(0),
+				"monster_spawn_block_light_limit": int32(0),
+				"infiniburn":                      "#minecraft:infiniburn_overworld",
+				"respawn_anchor_works":            false,
+				"has_skylight":                    true,
+				"bed_works":                       false,
+				"effects":                         "minecraft:overworld",
+				"has_raids":                       false,
+				"logical_height":                  int32(voidDimensionHeight),
+				"coordinate_scale":                float64(1),
+				"monster_spawn_light_level":       int32(0),
+				"min_y":                           int32(voidDimensionMinY),
+				"ultrawarm":                       false,
+				"has_ceiling":                     false,
+				"height":                          int32(voidDimensionHeight),
+			},
+		}}},
+		{key: "minecraft:worldgen/biome", entries: []registryEntry{{
+			name: voidBiome,
+			element: map[string]any{
+				"has_precipitation": false,
+				"temperature":       0.8,
+				"downfall":          0.4,
+				"effects": map[string]any{
+					"sky_color":       int32(7907327),
+					"water_fog_color": int32(329011),
+					"fog_color":       int32(12638463),
+					"water_color":     int32(4159204),
+				},
+			},
+		}}},
+		{key: "minecraft:chat_type", entries: []registryEntry{{
+			name: "minecraft:chat",
+			element: map[string]any{
+				"chat": map[string]any{
+					"translation_key": "chat.type.text",
+					"parameters":      []any{"sender", "content"},
+				},
+				"narration": map[string]any{
+					"translation_key": "chat.type.text.narrate",
+					"parameters":      []any{"sender", "content"},
+				},
+			},
+		}}},
+		{key: "minecraft:damage_type", entries: damageTypeEntries()},
+	}
+
+	if protocolVersion >= protocol1_20_1 {
+		registries = append(registries,
+			registry{key: "minecraft:trim_pattern"},
+			registry{key: "minecraft:trim_material"},
+		)
+	}
+	if protocolVersion >= protocol1_20_6 {
+		registries = append(registries, registry{key: "minecraft:wolf_variant", entries: []registryEntry{{
+			name: "minecraft:pale",
+			element: map[string]any{
+				"wild_texture":  "minecraft:entity/wolf/wolf",
+				"tame_texture":  "minecraft:entity/wolf/wolf_tame",
+				"angry_texture": "minecraft:entity/wolf/wolf_angry",
+				"biomes":        voidBiome,
+			},
+		}}})
+	}
+	if protocolVersion >= protocol1_21_1 {
+		registries = append(registries, registry{key: "minecraft:painting_variant", entries: []registryEntry{{
+			name: "minecraft:kebab",
+			element: map[string]any{
+				"asset_id": "minecraft:kebab",
+				"width":    int32(1),
+				"height":   int32(1),
+			},
+		}}})
+	}
+
+	return registries
+}
+
+func damageTypeEntries() []registryEntry {
+	entries := make([]registryEntry, 0, len(damageTypes))
+	for _, name := range damageTypes {
+		entries = append(entries, registryEntry{
+			name: "minecraft:" + name,
+			element: map[string]any{
+				"message_id": "generic",
+				"scaling":    "never",
+				"exhaustion": float64(0),
+			},
+		})
+	}
+
+	return entries
+}
+
+// registryCodec returns registries in the single compound used by Join Game
+// before 1.20.2 and by Registry Data in 1.20.2 - 1.20.4.
+func registryCodec(registries []registry) map[string]any {
+	codec := make(map[string]any, len(registries))
+	for _, r := range registries {
+		values := make([]any, 0, len(r.entries))
+		for id, entry := range r.entries {
+			values = append(values, map[string]any{
+				"name":    entry.name,
+				"id":      int32(id),
+				"element": entry.element,
+			})
+		}
+		codec[r.key] = map[string]any{
+			"type":  r.key,
+			"value": values,
+		}
+	}
+
+	return codec
+}
+
+// SendRegistryData sends the void world registries to a client in the
+// configuration state: one packet holding every registry up to 1.20.4, one
+// packet per registry from 1.20.5.
+func SendRegistryData(w io.Writer, protocolVersion int32) error {
+	version := VersionFor(protocolVersion)
+	if !version.HasConfigurationState {
+		return fmt.Errorf("protocol %d has no configuration state", protocolVersion)
+	}
+
+	registries := voidWorldRegistries(version.Protocol)
+	if version.Protocol < protocol1_20_6 {
+		payload := EncodeVarInt(version.Configuration.RegistryData)
+		payload, err := appendNetworkNBT(payload, registryCodec(registries))
+		if err != nil {
+			return fmt.Errorf("encode registry codec: %w", err)
+		}
+
+		_, err = w.Write(WrapPacket(payload))
+		return err
+	}
+
+	for _, r := range registries {
+		payload := EncodeVarInt(version.Configuration.RegistryData)
+		payload = appendString(payload, r.key)
+		payload = append(payload, EncodeVarInt(int32(len(r.entries)))...)
+		for _, entry := range r.entries {
+			payload = appendString(payload, entry.name)
+			payload = append(payload, 0x01) // has data
+			var err error
+			if payload, err = appendNetworkNBT(payload, entry.element); err != nil {
+				return fmt.Errorf("encode %s entry %s: %w", r.key, entry.name, err)
+			}
+		}
+
+		if _, err := w.Write(WrapPacket(payload)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendFeatureFlags enables the vanilla feature set, which the client
+// otherwise leaves empty when the server does not send it.
+func SendFeatureFlags(w io.Writer, protocolVersion int32) error {
+	version := VersionFor(protocolVersion)
+	if !version.HasConfigurationState {
+		return fmt.Errorf("protocol %d has no configuration state", protocolVersion)
+	}
+
+	payload := EncodeVarInt(version.Configuration.FeatureFlags)
+	payload = append(payload, EncodeVarInt(1)...)
+	payload = appendString(payload, "minecraft:vanilla")
+
+	_, err := w.Write(WrapPacket(payload))
+	return err
+}
+
+// SendFinishConfiguration moves the client to the play state once it answers
+// with Acknowledge Finish Configuration.
+func SendFinishConfiguration(w io.Writer, protocolVersion int32) error {
+	version := VersionFor(protocolVersion)
+	if !version.HasConfigurationState {
+		return fmt.Errorf("protocol %d has no configuration state", protocolVersion)
+	}
+
+	_, err := w.Write(WrapPacket(EncodeVarInt(version.Configuration.FinishConfiguration)))
+	return err
+}
+
+func appendString(dst []byte, value string) []byte {
+	dst = append(dst, EncodeVarInt(int32(len(value)))...)
+	return append(dst, value...)
+}
@@ -0,0 +1,98 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	faviconSize          = 64
+	faviconDataURIPrefix = "data:image/png;base64,"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+type StatusResponse struct {
+	Version     StatusVersion   `json:"version"`
+	Players     StatusPlayers   `json:"players"`
+	Description json.RawMessage `json:"description"`
+	// Favicon is a data URI ("data:image/png;base64,...") of a 64x64 PNG.
+	Favicon            string `json:"favicon,omitempty"`
+	EnforcesSecureChat bool   `json:"enforcesSecureChat"`
+	PreviewsChat       bool   `json:"previewsChat"`
+}
+
+type StatusVersion struct {
+	Name     string `json:"name"`
+	Protocol int32  `json:"protocol"`
+}
+
+type StatusPlayers struct {
+	Max    int32                `json:"max"`
+	Online int32                `json:"online"`
+	Sample []StatusPlayerSample `json:"sample,omitempty"`
+}
+
+type StatusPlayerSample struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// StatusDescription converts a MOTD into the status description: JSON text
+// components are sent as-is, § formatted text is converted to a component.
+func StatusDescription(motd string) (json.RawMessage, error) {
+	return textComponentPayload(motd)
+}
+
+// FaviconDataURI validates a PNG image and encodes it the way the status
+// response expects. The client only renders 64x64 images.
+func FaviconDataURI(png []byte) (string, error) {
+	if len(png) < len(pngSignature)+16 || !bytes.Equal(png[:len(pngSignature)], pngSignature) {
+		return "", fmt.Errorf("favicon is not a PNG image")
+	}
+
+	// The IHDR chunk always comes first: length, type, width, height.
+	ihdr := png[len(pngSignature):]
+	if string(ihdr[4:8]) != "IHDR" {
+		return "", fmt.Errorf("favicon PNG is missing the IHDR chunk")
+	}
+	width := binary.BigEndian.Uint32(ihdr[8:12])
+	height := binary.BigEndian.Uint32(ihdr[12:16])
+	if width != faviconSize || height != faviconSize {
+		return "", fmt.Errorf("favicon must be %dx%d, got %dx%d", faviconSize, faviconSize, width, height)
+	}
+
+	return faviconDataURIPrefix + base64.StdEncoding.EncodeToString(png), nil
+}
+
+func SendStatusResponse(w io.Writer, status StatusResponse) error {
+	response, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+
+	payload := make([]byte, 0, 1+len(response)+5)
+	payload = append(payload, statusResponsePacketID)
+	payload = append(payload, EncodeVarInt(int32(len(response)))...)
+	payload = append(payload, response...)
+
+	packetLen := EncodeVarInt(int32(len(payload)))
+	packet := append(packetLen, payload...)
+
+	_, err = w.Write(packet)
+	return err
+}
+
+func SendPong(w io.Writer, pingPayload []byte) error {
+	payload := make([]byte, 0, 1+len(pingPayload))
+	payload = append(payload, pongResponsePacketID)
+	payload = append(payload, pingPayload...)
+
+	packet := append(EncodeVarInt(int32(len(payload))), payload...)
+	_, err := w.Write(packet)
+	return err
+}
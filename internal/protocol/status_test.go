@@ -0,0 +1,98 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testPNG(width uint32, height uint32) []byte {
+	png := append([]byte(nil), pngSignature...)
+	png = binary.BigEndian.AppendUint32(png, 13)
+	png = append(png, "IHDR"...)
+	png = binary.BigEndian.AppendUint32(png, width)
+	png = binary.BigEndian.AppendUint32(png, height)
+	png = append(png, 8, 6, 0, 0, 0)
+	return png
+}
+
+func TestFaviconDataURI(t *testing.T) {
+	png := testPNG(64, 64)
+
+	uri, err := FaviconDataURI(png)
+	if err != nil {
+		t.Fatalf("FaviconDataURI failed: %v", err)
+	}
+	if !strings.HasPrefix(uri, "data:image/png;base64,") {
+		t.Fatalf("unexpected favicon prefix: %q", uri)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(uri, "data:image/png;base64,"))
+	if err != nil || !bytes.Equal(decoded, png) {
+		t.Fatalf("favicon does not round-trip: %v", err)
+	}
+
+	if _, err := FaviconDataURI(testPNG(128, 128)); err == nil {
+		t.Fatal("expected error for non 64x64 favicon")
+	}
+	if _, err := FaviconDataURI([]byte("GIF89a not a png at all")); err == nil {
+		t.Fatal("expected error for non-PNG favicon")
+	}
+}
+
+func TestStatusDescription_KeepsJSONComponents(t *testing.T) {
+	raw := `{"text":"Mine","color":"red","extra":[{"text":"Mock","bold":true}]}`
+
+	description, err := StatusDescription(raw)
+	if err != nil {
+		t.Fatalf("StatusDescription failed: %v", err)
+	}
+	if string(description) != raw {
+		t.Fatalf("expected raw component to be preserved, got %s", description)
+	}
+
+	description, err = StatusDescription("404")
+	if err != nil {
+		t.Fatalf("StatusDescription failed: %v", err)
+	}
+	if string(description) != `{"text":"404"}` {
+		t.Fatalf("expected numeric MOTD to be wrapped as text, got %s", description)
+	}
+}
+
+func TestStatusResponse_JSONFields(t *testing.T) {
+	response := StatusResponse{
+		Version: StatusVersion{Name: "1.20.1", Protocol: 763},
+		Players: StatusPlayers{
+			Max:    20,
+			Online: 2,
+			Sample: []StatusPlayerSample{{Name: "Steve", ID: OfflinePlayerUUID("Steve").String()}},
+		},
+		Description:        json.RawMessage(`{"text":"MineMock"}`),
+		Favicon:            "data:image/png;base64,AA==",
+		EnforcesSecureChat: true,
+	}
+
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("json marshal failed: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("json unmarshal failed: %v", err)
+	}
+	for _, key := range []string{"version", "players", "description", "favicon", "enforcesSecureChat", "previewsChat"} {
+		if _, ok := decoded[key]; !ok {
+			t.Fatalf("expected %q in status json: %s", key, encoded)
+		}
+	}
+
+	sample := decoded["players"].(map[string]any)["sample"].([]any)
+	player := sample[0].(map[string]any)
+	if player["name"] != "Steve" || player["id"] != OfflinePlayerUUID("Steve").String() {
+		t.Fatalf("unexpected player sample: %v", player)
+	}
+}
@@ -0,0 +1,338 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const legacyFormattingChar = '§'
+
+// TextComponent is the subset of the chat component format produced from
+// legacy formatted strings. Styles are never inherited between siblings, so
+// false values can be omitted.
+type TextComponent struct {
+	Text          string          `json:"text"`
+	Color         string          `json:"color,omitempty"`
+	Bold          bool            `json:"bold,omitempty"`
+	Italic        bool            `json:"italic,omitempty"`
+	Underlined    bool            `json:"underlined,omitempty"`
+	Strikethrough bool            `json:"strikethrough,omitempty"`
+	Obfuscated    bool            `json:"obfuscated,omitempty"`
+	Extra         []TextComponent `json:"extra,omitempty"`
+}
+
+var legacyColors = map[rune]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+}
+
+var legacyColorCodes = func() map[string]rune {
+	codes := make(map[string]rune, len(legacyColors))
+	for code, color := range legacyColors {
+		codes[color] = code
+	}
+	return codes
+}()
+
+type textStyle struct {
+	color         string
+	bold          bool
+	italic        bool
+	underlined    bool
+	strikethrough bool
+	obfuscated    bool
+}
+
+// ParseLegacyText converts a string with § formatting codes into a text
+// component. Text without codes becomes a single {"text": ...} component;
+// otherwise every styled run is a child of an empty root component. The
+// Spigot hex form §x§R§R§G§G§B§B is understood as well.
+func ParseLegacyText(text string) TextComponent {
+	runes := []rune(text)
+
+	var segments []TextComponent
+	var current strings.Builder
+	style := textStyle{}
+	formatted := false
+
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		segments = append(segments, style.component(current.String()))
+		current.Reset()
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != legacyFormattingChar || i+1 >= len(runes) {
+			current.WriteRune(runes[i])
+			continue
+		}
+
+		code := toLowerASCII(runes[i+1])
+		if code == 'x' {
+			if color, ok := legacyHexColor(runes[i+2:]); ok {
+				flush()
+				formatted = true
+				style = textStyle{color: color}
+				i += 13
+				continue
+			}
+		}
+
+		next, ok := style.apply(code)
+		if !ok {
+			current.WriteRune(runes[i])
+			continue
+		}
+
+		flush()
+		formatted = true
+		style = next
+		i++
+	}
+	flush()
+
+	if !formatted {
+		return TextComponent{Text: text}
+	}
+	if len(segments) == 0 {
+		return TextComponent{}
+	}
+
+	return TextComponent{Extra: segments}
+}
+
+// TranslateAlternateColorCodes replaces altChar followed by a formatting code
+// (for example "&c") with the § form, leaving other occurrences untouched.
+func TranslateAlternateColorCodes(altChar rune, text string) string {
+	runes := []rune(text)
+	for i := 0; i+1 < len(runes); i++ {
+		if runes[i] != altChar || !isLegacyCode(toLowerASCII(runes[i+1])) {
+			continue
+		}
+		runes[i] = legacyFormattingChar
+		i++
+	}
+
+	return string(runes)
+}
+
+// ToLegacyText renders a message for logs and legacy clients: JSON text
+// components are converted to § formatted text, anything else is returned
+// unchanged.
+func ToLegacyText(message string) string {
+	trimmed := strings.TrimSpace(message)
+	if !isJSONTextComponent(trimmed) {
+		return message
+	}
+
+	legacy, err := ComponentToLegacy([]byte(trimmed))
+	if err != nil {
+		return message
+	}
+
+	return legacy
+}
+
+// ComponentToLegacy flattens a JSON text component into § formatted text.
+// Translatable components are rendered as their translation key.
+func ComponentToLegacy(component []byte) (string, error) {
+	var decoded any
+	if err := json.Unmarshal(component, &decoded); err != nil {
+		return "", fmt.Errorf("decode text component: %w", err)
+	}
+
+	renderer := legacyRenderer{}
+	renderer.render(decoded, textStyle{})
+
+	return renderer.builder.String(), nil
+}
+
+type legacyRenderer struct {
+	builder strings.Builder
+	emitted textStyle
+}
+
+func (r *legacyRenderer) render(node any, inherited textStyle) {
+	switch value := node.(type) {
+	case string:
+		r.write(value, inherited)
+	case []any:
+		for _, child := range value {
+			r.render(child, inherited)
+		}
+	case map[string]any:
+		style := inherited.merge(value)
+
+		if text, ok := value["text"].(string); ok {
+			r.write(text, style)
+		} else if key, ok := value["translate"].(string); ok {
+			r.write(key, style)
+		}
+
+		if extra, ok := value["extra"].([]any); ok {
+			for _, child := range extra {
+				r.render(child, style)
+			}
+		}
+	default:
+		if value != nil {
+			r.write(fmt.Sprint(value), inherited)
+		}
+	}
+}
+
+func (r *legacyRenderer) write(text string, style textStyle) {
+	if text == "" {
+		return
+	}
+
+	if style != r.emitted {
+		r.builder.WriteString(style.legacyCodes())
+		r.emitted = style
+	}
+	r.builder.WriteString(text)
+}
+
+func (s textStyle) apply(code rune) (textStyle, bool) {
+	if color, ok := legacyColors[code]; ok {
+		return textStyle{color: color}, true
+	}
+
+	switch code {
+	case 'k':
+		s.obfuscated = true
+	case 'l':
+		s.bold = true
+	case 'm':
+		s.strikethrough = true
+	case 'n':
+		s.underlined = true
+	case 'o':
+		s.italic = true
+	case 'r':
+		s = textStyle{}
+	default:
+		return s, false
+	}
+
+	return s, true
+}
+
+func (s textStyle) merge(component map[string]any) textStyle {
+	if color, ok := component["color"].(string); ok {
+		s.color = color
+	}
+
+	flags := map[string]*bool{
+		"bold":          &s.bold,
+		"italic":        &s.italic,
+		"underlined":    &s.underlined,
+		"strikethrough": &s.strikethrough,
+		"obfuscated":    &s.obfuscated,
+	}
+	for key, flag := range flags {
+		if value, ok := component[key].(bool); ok {
+			*flag = value
+		}
+	}
+
+	return s
+}
+
+func (s textStyle) component(text string) TextComponent {
+	return TextComponent{
+		Text:          text,
+		Color:         s.color,
+		Bold:          s.bold,
+		Italic:        s.italic,
+		Underlined:    s.underlined,
+		Strikethrough: s.strikethrough,
+		Obfuscated:    s.obfuscated,
+	}
+}
+
+// legacyCodes returns the codes that switch any previous style to s. A color
+// code resets formatting on its own, otherwise §r is needed first.
+func (s textStyle) legacyCodes() string {
+	var builder strings.Builder
+
+	switch {
+	case strings.HasPrefix(s.color, "#") && len(s.color) == 7:
+		builder.WriteString("§x")
+		for _, digit := range s.color[1:] {
+			builder.WriteRune(legacyFormattingChar)
+			builder.WriteRune(digit)
+		}
+	case legacyColorCodes[s.color] != 0:
+		builder.WriteRune(legacyFormattingChar)
+		builder.WriteRune(legacyColorCodes[s.color])
+	default:
+		builder.WriteString("§r")
+	}
+
+	flags := []struct {
+		enabled bool
+		code    rune
+	}{
+		{s.obfuscated, 'k'},
+		{s.bold, 'l'},
+		{s.strikethrough, 'm'},
+		{s.underlined, 'n'},
+		{s.italic, 'o'},
+	}
+	for _, flag := range flags {
+		if flag.enabled {
+			builder.WriteRune(legacyFormattingChar)
+			builder.WriteRune(flag.code)
+		}
+	}
+
+	return builder.String()
+}
+
+// legacyHexColor reads the six §-prefixed digits that follow §x.
+func legacyHexColor(runes []rune) (string, bool) {
+	if len(runes) < 12 {
+		return "", false
+	}
+
+	digits := make([]rune, 0, 6)
+	for i := 0; i < 12; i += 2 {
+		digit := toLowerASCII(runes[i+1])
+		if runes[i] != legacyFormattingChar || !strings.ContainsRune("0123456789abcdef", digit) {
+			return "", false
+		}
+		digits = append(digits, digit)
+	}
+
+	return "#" + string(digits), true
+}
+
+func isLegacyCode(code rune) bool {
+	_, ok := textStyle{}.apply(code)
+	return ok || code == 'x'
+}
+
+func toLowerASCII(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + ('a' - 'A')
+	}
+	return r
+}
@@ -0,0 +1,92 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseLegacyText_PlainText(t *testing.T) {
+	component := ParseLegacyText("Server is working")
+
+	encoded, err := json.Marshal(component)
+	if err != nil {
+		t.Fatalf("json marshal failed: %v", err)
+	}
+	if string(encoded) != `{"text":"Server is working"}` {
+		t.Fatalf("unexpected plain component: %s", encoded)
+	}
+}
+
+func TestParseLegacyText_Formatting(t *testing.T) {
+	component := ParseLegacyText("§c§oMine§4§lMock§r\n§7ok §zliteral")
+
+	expected := []TextComponent{
+		{Text: "Mine", Color: "red", Italic: true},
+		{Text: "Mock", Color: "dark_red", Bold: true},
+		{Text: "\n"},
+		{Text: "ok §zliteral", Color: "gray"},
+	}
+
+	if component.Text != "" || len(component.Extra) != len(expected) {
+		t.Fatalf("unexpected component: %+v", component)
+	}
+	for i, segment := range expected {
+		got := component.Extra[i]
+		if got.Text != segment.Text || got.Color != segment.Color || got.Bold != segment.Bold || got.Italic != segment.Italic {
+			t.Fatalf("segment %d: expected %+v, got %+v", i, segment, got)
+		}
+	}
+}
+
+func TestParseLegacyText_HexColor(t *testing.T) {
+	component := ParseLegacyText("§x§F§F§8§8§0§0Orange")
+
+	if len(component.Extra) != 1 || component.Extra[0].Color != "#ff8800" || component.Extra[0].Text != "Orange" {
+		t.Fatalf("unexpected hex color component: %+v", component)
+	}
+}
+
+func TestTranslateAlternateColorCodes(t *testing.T) {
+	got := TranslateAlternateColorCodes('&', "&aGreen & &lbold&&")
+	if got != "§aGreen & §lbold&&" {
+		t.Fatalf("unexpected translation: %q", got)
+	}
+}
+
+func TestComponentToLegacy_RoundTrip(t *testing.T) {
+	legacy := "§c§oMine§4§lMock§r plain §x§f§f§8§8§0§0hex"
+
+	encoded, err := json.Marshal(ParseLegacyText(legacy))
+	if err != nil {
+		t.Fatalf("json marshal failed: %v", err)
+	}
+
+	got, err := ComponentToLegacy(encoded)
+	if err != nil {
+		t.Fatalf("ComponentToLegacy failed: %v", err)
+	}
+	if got != legacy {
+		t.Fatalf("expected %q, got %q", legacy, got)
+	}
+}
+
+func TestComponentToLegacy_InheritsStyle(t *testing.T) {
+	raw := `{"text":"A","color":"gold","extra":["B",{"text":"C","bold":true},{"translate":"disconnect.lost","color":"red"}]}`
+
+	got, err := ComponentToLegacy([]byte(raw))
+	if err != nil {
+		t.Fatalf("ComponentToLegacy failed: %v", err)
+	}
+	if got != "§6AB§6§lC§cdisconnect.lost" {
+		t.Fatalf("unexpected legacy text: %q", got)
+	}
+}
+
+func TestToLegacyText(t *testing.T) {
+	if got := ToLegacyText("§aplain"); got != "§aplain" {
+		t.Fatalf("expected non-JSON text to be unchanged, got %q", got)
+	}
+	if got := ToLegacyText(` {"text":"json","color":"green"} `); got != "§ajson" {
+		t.Fatalf("unexpected legacy text for component: %q", got)
+	}
+}
@@ -0,0 +1,40 @@
+package protocol
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type UUID [16]byte
+
+// OfflinePlayerUUID returns the UUID a vanilla offline-mode server assigns to
+// username: a version 3 UUID built from the MD5 of "OfflinePlayer:<name>".
+func OfflinePlayerUUID(username string) UUID {
+	uuid := UUID(md5.Sum([]byte("OfflinePlayer:" + username)))
+	uuid[6] = uuid[6]&0x0F | 0x30
+	uuid[8] = uuid[8]&0x3F | 0x80
+
+	return uuid
+}
+
+// ParseUUID accepts both the dashed and the undashed (Mojang API) forms.
+func ParseUUID(value string) (UUID, error) {
+	trimmed := strings.ReplaceAll(strings.TrimSpace(value), "-", "")
+	if len(trimmed) != 32 {
+		return UUID{}, fmt.Errorf("invalid uuid %q", value)
+	}
+
+	var uuid UUID
+	if _, err := hex.Decode(uuid[:], []byte(trimmed)); err != nil {
+		return UUID{}, fmt.Errorf("invalid uuid %q: %w", value, err)
+	}
+
+	return uuid, nil
+}
+
+func (u UUID) String() string {
+	encoded := hex.EncodeToString(u[:])
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:32]
+}
@@ -0,0 +1,37 @@
+package protocol
+
+import "testing"
+
+func TestOfflinePlayerUUID(t *testing.T) {
+	uuid := OfflinePlayerUUID("Notch")
+	if got := uuid.String(); got != "b50ad385-829d-3141-a216-7e7d7539ba7f" {
+		t.Fatalf("unexpected offline uuid for Notch: %s", got)
+	}
+	if OfflinePlayerUUID("Notch") != uuid {
+		t.Fatal("expected offline uuid to be deterministic")
+	}
+	if OfflinePlayerUUID("notch") == uuid {
+		t.Fatal("expected offline uuid to be case-sensitive like vanilla")
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	dashed, err := ParseUUID("069a79f4-44e9-4726-a5be-fca90e38aaf5")
+	if err != nil {
+		t.Fatalf("ParseUUID failed: %v", err)
+	}
+	undashed, err := ParseUUID("069a79f444e94726a5befca90e38aaf5")
+	if err != nil {
+		t.Fatalf("ParseUUID failed: %v", err)
+	}
+	if dashed != undashed {
+		t.Fatalf("expected dashed and undashed forms to match: %s != %s", dashed, undashed)
+	}
+	if dashed.String() != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Fatalf("unexpected uuid string: %s", dashed)
+	}
+
+	if _, err := ParseUUID("not-a-uuid"); err == nil {
+		t.Fatal("expected error for invalid uuid")
+	}
+}
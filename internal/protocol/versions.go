@@ -0,0 +1,328 @@
+package protocol
+
+import "sort"
+
+const (
+	protocol1_19   int32 = 759
+	protocol1_19_2 int32 = 760
+	protocol1_19_4 int32 = 762
+	protocol1_20_1 int32 = 763
+	protocol1_20_2 int32 = 764
+	protocol1_20_4 int32 = 765
+	protocol1_20_6 int32 = 766
+	protocol1_21_1 int32 = 767
+	protocol1_21_3 int32 = 768
+	protocol1_21_4 int32 = 769
+)
+
+// Status packet ids have not changed since the netty rewrite (1.7), so they
+// are not part of the per-version tables.
+const (
+	statusResponsePacketID = 0x00
+	pongResponsePacketID   = 0x01
+)
+
+type Version struct {
+	Protocol      int32
+	Login         LoginPacketIDs
+	Configuration ConfigurationPacketIDs
+	Play          PlayPacketIDs
+
+	// HasConfigurationState is true from 1.20.2, where the client acknowledges
+	// Login Success and enters the configuration state before play.
+	HasConfigurationState bool
+	// LoginSuccessStrictErrorHandling is true for 1.20.5 - 1.21.1, where
+	// Login Success ends with a "strict error handling" boolean.
+	LoginSuccessStrictErrorHandling bool
+	// EncryptionRequestShouldAuthenticate is true from 1.20.5, where Encryption
+	// Request tells the client whether to contact the session server.
+	EncryptionRequestShouldAuthenticate bool
+	// SupportsTransfer is true from 1.20.5, which added the Transfer packet to
+	// the configuration and play states.
+	SupportsTransfer bool
+	// SupportsCookies is true from 1.20.5, which added Cookie Request, Store
+	// Cookie and Cookie Response.
+	SupportsCookies bool
+	// NBTTextComponents is true from 1.20.3, where text components outside the
+	// login state are sent as network NBT instead of JSON strings.
+	NBTTextComponents bool
+	// WaitsForLevelChunks is true from 1.20.3, where the client keeps the
+	// loading screen up until the "start waiting for level chunks" game event.
+	WaitsForLevelChunks bool
+}
+
+type LoginPacketIDs struct {
+	Disconnect         int32
+	EncryptionRequest  int32
+	LoginSuccess       int32
+	SetCompression     int32
+	PluginRequest      int32
+	EncryptionResponse int32
+	PluginResponse     int32
+	LoginAcknowledged  int32
+	// CookieRequest and CookieResponse only exist from 1.20.5.
+	CookieRequest  int32
+	CookieResponse int32
+}
+
+type ConfigurationPacketIDs struct {
+	Disconnect             int32
+	FinishConfiguration    int32
+	RegistryData           int32
+	FeatureFlags           int32
+	FinishConfigurationAck int32
+	// Transfer and Store Cookie only exist from 1.20.5.
+	Transfer    int32
+	StoreCookie int32
+}
+
+type PlayPacketIDs struct {
+	Disconnect              int32
+	Login                   int32
+	KeepAlive               int32
+	GameEvent               int32
+	SyncPlayerPosition      int32
+	SetDefaultSpawnPosition int32
+	SystemChat              int32
+	SetTitleText            int32
+	SetSubtitleText         int32
+	SetTitleAnimationTimes  int32
+	BossBar                 int32
+	KeepAliveResponse       int32
+	// Transfer only exists from 1.20.5.
+	Transfer int32
+}
+
+var loginPacketIDs = LoginPacketIDs{
+	Disconnect:         0x00,
+	EncryptionRequest:  0x01,
+	LoginSuccess:       0x02,
+	SetCompression:     0x03,
+	PluginRequest:      0x04,
+	EncryptionResponse: 0x01,
+	PluginResponse:     0x02,
+	LoginAcknowledged:  0x03,
+	CookieRequest:      0x05,
+	CookieResponse:     0x04,
+}
+
+var (
+	configurationPacketIDs1_20_2 = ConfigurationPacketIDs{
+		Disconnect:             0x01,
+		FinishConfiguration:    0x02,
+		RegistryData:           0x05,
+		FeatureFlags:           0x07,
+		FinishConfigurationAck: 0x02,
+	}
+	configurationPacketIDs1_20_3 = ConfigurationPacketIDs{
+		Disconnect:             0x01,
+		FinishConfiguration:    0x02,
+		RegistryData:           0x05,
+		FeatureFlags:           0x08,
+		FinishConfigurationAck: 0x02,
+	}
+	configurationPacketIDs1_20_5 = ConfigurationPacketIDs{
+		Disconnect:             0x02,
+		FinishConfiguration:    0x03,
+		RegistryData:           0x07,
+		FeatureFlags:           0x0C,
+		FinishConfigurationAck: 0x03,
+		Transfer:               0x0B,
+		StoreCookie:            0x0A,
+	}
+)
+
+var (
+	playPacketIDs1_19_4 = PlayPacketIDs{
+		Disconnect:              0x1A,
+		Login:                   0x28,
+		KeepAlive:               0x23,
+		GameEvent:               0x1F,
+		SyncPlayerPosition:      0x3C,
+		SetDefaultSpawnPosition: 0x50,
+		SystemChat:              0x64,
+		SetTitleText:            0x5F,
+		SetSubtitleText:         0x5D,
+		SetTitleAnimationTimes:  0x60,
+		BossBar:                 0x0B,
+		KeepAliveResponse:       0x12,
+	}
+	playPacketIDs1_20_2 = PlayPacketIDs{
+		Disconnect:              0x1B,
+		Login:                   0x29,
+		KeepAlive:               0x24,
+		GameEvent:               0x20,
+		SyncPlayerPosition:      0x3E,
+		SetDefaultSpawnPosition: 0x52,
+		SystemChat:              0x67,
+		SetTitleText:            0x61,
+		SetSubtitleText:         0x5F,
+		SetTitleAnimationTimes:  0x62,
+		BossBar:                 0x0A,
+		KeepAliveResponse:       0x14,
+	}
+	playPacketIDs1_20_3 = PlayPacketIDs{
+		Disconnect:              0x1B,
+		Login:                   0x29,
+		KeepAlive:               0x24,
+		GameEvent:               0x20,
+		SyncPlayerPosition:      0x3E,
+		SetDefaultSpawnPosition: 0x54,
+		SystemChat:              0x69,
+		SetTitleText:            0x63,
+		SetSubtitleText:         0x61,
+		SetTitleAnimationTimes:  0x64,
+		BossBar:                 0x0A,
+		KeepAliveResponse:       0x15,
+	}
+	playPacketIDs1_20_5 = PlayPacketIDs{
+		Disconnect:              0x1D,
+		Login:                   0x2B,
+		KeepAlive:               0x26,
+		GameEvent:               0x22,
+		SyncPlayerPosition:      0x40,
+		SetDefaultSpawnPosition: 0x56,
+		SystemChat:              0x6C,
+		SetTitleText:            0x65,
+		SetSubtitleText:         0x63,
+		SetTitleAnimationTimes:  0x66,
+		BossBar:                 0x0A,
+		KeepAliveResponse:       0x18,
+		Transfer:                0x73,
+	}
+	playPacketIDs1_21_2 = PlayPacketIDs{
+		Disconnect:              0x1D,
+		Login:                   0x2C,
+		KeepAlive:               0x27,
+		GameEvent:               0x23,
+		SyncPlayerPosition:      0x42,
+		SetDefaultSpawnPosition: 0x5B,
+		SystemChat:              0x73,
+		SetTitleText:            0x6C,
+		SetSubtitleText:         0x6A,
+		SetTitleAnimationTimes:  0x6D,
+		BossBar:                 0x0A,
+		KeepAliveResponse:       0x1A,
+		Transfer:                0x7A,
+	}
+)
+
+var versions = map[int32]Version{
+	protocol1_19_4: {
+		Protocol: protocol1_19_4,
+		Login:    loginPacketIDs,
+		Play:     playPacketIDs1_19_4,
+	},
+	protocol1_20_1: {
+		Protocol: protocol1_20_1,
+		Login:    loginPacketIDs,
+		Play:     playPacketIDs1_19_4,
+	},
+	protocol1_20_2: {
+		Protocol:              protocol1_20_2,
+		Login:                 loginPacketIDs,
+		Configuration:         configurationPacketIDs1_20_2,
+		Play:                  playPacketIDs1_20_2,
+		HasConfigurationState: true,
+	},
+	protocol1_20_4: {
+		Protocol:              protocol1_20_4,
+		Login:                 loginPacketIDs,
+		Configuration:         configurationPacketIDs1_20_3,
+		Play:                  playPacketIDs1_20_3,
+		HasConfigurationState: true,
+		NBTTextComponents:     true,
+		WaitsForLevelChunks:   true,
+	},
+	protocol1_20_6: {
+		Protocol:                            protocol1_20_6,
+		Login:                               loginPacketIDs,
+		Configuration:                       configurationPacketIDs1_20_5,
+		Play:                                playPacketIDs1_20_5,
+		HasConfigurationState:               true,
+		LoginSuccessStrictErrorHandling:     true,
+		NBTTextComponents:                   true,
+		EncryptionRequestShouldAuthenticate: true,
+		SupportsTransfer:                    true,
+		SupportsCookies:                     true,
+		WaitsForLevelChunks:                 true,
+	},
+	protocol1_21_1: {
+		Protocol:                            protocol1_21_1,
+		Login:                               loginPacketIDs,
+		Configuration:                       configurationPacketIDs1_20_5,
+		Play:                                playPacketIDs1_20_5,
+		HasConfigurationState:               true,
+		LoginSuccessStrictErrorHandling:     true,
+		NBTTextComponents:                   true,
+		EncryptionRequestShouldAuthenticate: true,
+		SupportsTransfer:                    true,
+		SupportsCookies:                     true,
+		WaitsForLevelChunks:                 true,
+	},
+	protocol1_21_3: {
+		Protocol:                            protocol1_21_3,
+		Login:                               loginPacketIDs,
+		Configuration:                       configurationPacketIDs1_20_5,
+		Play:                                playPacketIDs1_21_2,
+		HasConfigurationState:               true,
+		NBTTextComponents:                   true,
+		EncryptionRequestShouldAuthenticate: true,
+		SupportsTransfer:                    true,
+		SupportsCookies:                     true,
+		WaitsForLevelChunks:                 true,
+	},
+	protocol1_21_4: {
+		Protocol:                            protocol1_21_4,
+		Login:                               loginPacketIDs,
+		Configuration:                       configurationPacketIDs1_20_5,
+		Play:                                playPacketIDs1_21_2,
+		HasConfigurationState:               true,
+		NBTTextComponents:                   true,
+		EncryptionRequestShouldAuthenticate: true,
+		SupportsTransfer:                    true,
+		SupportsCookies:                     true,
+		WaitsForLevelChunks:                 true,
+	},
+}
+
+// LookupVersion returns the packet table for an exact protocol number.
+func LookupVersion(protocolVersion int32) (Version, bool) {
+	version, ok := versions[protocolVersion]
+	return version, ok
+}
+
+// VersionFor returns the packet table for protocolVersion, falling back to the
+// closest supported protocol for clients outside the known range.
+func VersionFor(protocolVersion int32) Version {
+	if version, ok := versions[protocolVersion]; ok {
+		return version
+	}
+
+	supported := SupportedProtocols()
+	if protocolVersion < supported[0] {
+		return versions[supported[0]]
+	}
+
+	closest := supported[0]
+	for _, candidate := range supported {
+		if candidate > protocolVersion {
+			break
+		}
+		closest = candidate
+	}
+
+	return versions[closest]
+}
+
+func SupportedProtocols() []int32 {
+	supported := make([]int32, 0, len(versions))
+	for protocolVersion := range versions {
+		supported = append(supported, protocolVersion)
+	}
+	sort.Slice(supported, func(i, j int) bool {
+		return supported[i] < supported[j]
+	})
+
+	return supported
+}
@@ -0,0 +1,182 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestVersionFor_FallsBackToClosestProtocol(t *testing.T) {
+	cases := map[int32]int32{
+		700:  762,
+		763:  763,
+		766:  766,
+		9999: 769,
+	}
+
+	for requested, expected := range cases {
+		if got := VersionFor(requested).Protocol; got != expected {
+			t.Fatalf("VersionFor(%d): expected protocol %d, got %d", requested, expected, got)
+		}
+	}
+}
+
+func TestSendPlayDisconnect_UsesVersionPacketID(t *testing.T) {
+	cases := map[int32]int32{
+		762: 0x1A,
+		763: 0x1A,
+		764: 0x1B,
+		765: 0x1B,
+		766: 0x1D,
+		769: 0x1D,
+	}
+
+	for protocolVersion, expectedID := range cases {
+		var out bytes.Buffer
+		if err := SendPlayDisconnect(&out, protocolVersion, "bye"); err != nil {
+			t.Fatalf("SendPlayDisconnect(%d) failed: %v", protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		packetID, _, err := ReadPacketID(packet)
+		if err != nil {
+			t.Fatalf("ReadPacketID failed: %v", err)
+		}
+		if packetID != expectedID {
+			t.Fatalf("protocol %d: expected play disconnect id 0x%02X, got 0x%02X", protocolVersion, expectedID, packetID)
+		}
+	}
+}
+
+func TestSendLoginSuccess_StrictErrorHandlingLayout(t *testing.T) {
+	cases := map[int32]bool{
+		763: false,
+		765: false,
+		766: true,
+		767: true,
+		768: false,
+	}
+
+	for protocolVersion, hasStrictFlag := range cases {
+		var out bytes.Buffer
+		if err := SendLoginSuccess(&out, protocolVersion, OfflinePlayerUUID("Steve"), "Steve"); err != nil {
+			t.Fatalf("SendLoginSuccess(%d) failed: %v", protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+
+		// id + uuid + username length + username + properties count
+		expectedLen := 1 + 16 + 1 + len("Steve") + 1
+		if hasStrictFlag {
+			expectedLen++
+		}
+		if len(packet) != expectedLen {
+			t.Fatalf("protocol %d: expected login success length %d, got %d", protocolVersion, expectedLen, len(packet))
+		}
+	}
+}
+
+func TestSendConfigurationDisconnect_UsesVersionPacketID(t *testing.T) {
+	cases := map[int32]int32{
+		764: 0x01,
+		765: 0x01,
+		766: 0x02,
+		769: 0x02,
+	}
+
+	for protocolVersion, expectedID := range cases {
+		var out bytes.Buffer
+		if err := SendConfigurationDisconnect(&out, protocolVersion, "bye"); err != nil {
+			t.Fatalf("SendConfigurationDisconnect(%d) failed: %v", protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		packetID, _, err := ReadPacketID(packet)
+		if err != nil {
+			t.Fatalf("ReadPacketID failed: %v", err)
+		}
+		if packetID != expectedID {
+			t.Fatalf("protocol %d: expected configuration disconnect id 0x%02X, got 0x%02X", protocolVersion, expectedID, packetID)
+		}
+	}
+}
+
+func TestSendConfigurationDisconnect_RejectsPreConfigurationProtocol(t *testing.T) {
+	var out bytes.Buffer
+	if err := SendConfigurationDisconnect(&out, 763, "bye"); err == nil {
+		t.Fatal("expected error for protocol without configuration state")
+	}
+	if out.Len() != 0 {
+		t.Fatalf("expected nothing to be written, got %d bytes", out.Len())
+	}
+}
+
+func TestReadLoginAcknowledged(t *testing.T) {
+	if err := ReadLoginAcknowledged(EncodeVarInt(0x03), 764); err != nil {
+		t.Fatalf("ReadLoginAcknowledged failed: %v", err)
+	}
+	if err := ReadLoginAcknowledged(EncodeVarInt(0x02), 764); err == nil {
+		t.Fatal("expected error for unexpected packet id")
+	}
+}
+
+func TestSendTransfer_UsesVersionPacketIDs(t *testing.T) {
+	cases := []struct {
+		protocolVersion int32
+		send            func(w *bytes.Buffer, protocolVersion int32) error
+		expectedID      int32
+	}{
+		{766, sendConfigurationTransferForTest, 0x0B},
+		{769, sendConfigurationTransferForTest, 0x0B},
+		{766, sendPlayTransferForTest, 0x73},
+		{767, sendPlayTransferForTest, 0x73},
+		{768, sendPlayTransferForTest, 0x7A},
+		{769, sendPlayTransferForTest, 0x7A},
+	}
+
+	for _, tc := range cases {
+		var out bytes.Buffer
+		if err := tc.send(&out, tc.protocolVersion); err != nil {
+			t.Fatalf("transfer for protocol %d failed: %v", tc.protocolVersion, err)
+		}
+
+		packet, err := ReadPacket(&out)
+		if err != nil {
+			t.Fatalf("ReadPacket failed: %v", err)
+		}
+		packetID, payload, err := ReadPacketID(packet)
+		if err != nil {
+			t.Fatalf("ReadPacketID failed: %v", err)
+		}
+		if packetID != tc.expectedID {
+			t.Fatalf("protocol %d: expected transfer id 0x%02X, got 0x%02X", tc.protocolVersion, tc.expectedID, packetID)
+		}
+
+		expected := append([]byte{byte(len("lobby"))}, "lobby"...)
+		expected = append(expected, EncodeVarInt(25570)...)
+		if !bytes.Equal(payload, expected) {
+			t.Fatalf("unexpected transfer payload: %v", payload)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := SendConfigurationTransfer(&out, 765, "lobby", 25570); err == nil {
+		t.Fatal("expected transfer to be rejected before 1.20.5")
+	}
+}
+
+func sendConfigurationTransferForTest(w *bytes.Buffer, protocolVersion int32) error {
+	return SendConfigurationTransfer(w, protocolVersion, "lobby", 25570)
+}
+
+func sendPlayTransferForTest(w *bytes.Buffer, protocolVersion int32) error {
+	return SendPlayTransfer(w, protocolVersion, "lobby", 25570)
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+// Hooks are called from the connection goroutines as clients go through the
+// server, so they must be safe for concurrent use. Nil hooks are skipped.
+type Hooks struct {
+	OnHandshake        func(HandshakeEvent)
+	OnLogin            func(LoginEvent)
+	OnConnectionClosed func(ConnectionClosedEvent)
+}
+
+type HandshakeEvent struct {
+	RemoteAddr net.Addr
+	Handshake  protocol.Handshake
+}
+
+// LoginEvent is reported once Login Start has been read, before online-mode
+// authentication. UUID is the one MineMock would send in Login Success and
+// ClientUUID the one from Login Start, if any.
+type LoginEvent struct {
+	RemoteAddr      net.Addr
+	Username        string
+	UUID            protocol.UUID
+	ClientUUID      string
+	ProtocolVersion int32
+	// Proxied is set when the player is sent to RealServerAddr.
+	Proxied bool
+}
+
+type ConnectionClosedEvent struct {
+	RemoteAddr net.Addr
+	Duration   time.Duration
+}
+
+func (h Hooks) handshake(conn net.Conn, handshake protocol.Handshake) {
+	if h.OnHandshake != nil {
+		h.OnHandshake(HandshakeEvent{RemoteAddr: conn.RemoteAddr(), Handshake: handshake})
+	}
+}
+
+func (h Hooks) login(conn net.Conn, event LoginEvent) {
+	if h.OnLogin != nil {
+		event.RemoteAddr = conn.RemoteAddr()
+		h.OnLogin(event)
+	}
+}
+
+func (h Hooks) connectionClosed(conn net.Conn, connectedAt time.Time) {
+	if h.OnConnectionClosed != nil {
+		h.OnConnectionClosed(ConnectionClosedEvent{RemoteAddr: conn.RemoteAddr(), Duration: time.Since(connectedAt)})
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+// keepAliveTimeoutMessage is the vanilla reason for clients that stop
+// answering Keep Alive.
+const keepAliveTimeoutMessage = `{"translate":"disconnect.timeout"}`
+
+const (
+	defaultKeepAliveInterval = 10 * time.Second
+	defaultKeepAliveTimeout  = 15 * time.Second
+)
+
+var errKeepAliveTimeout = errors.New("no keep alive response")
+
+// keepAlive sends Keep Alive packets to a play-state client and verifies the
+// ids it echoes back. Like on a vanilla server, only one challenge is pending
+// at a time.
+type keepAlive struct {
+	client          *packetConn
+	protocolVersion int32
+	timeout         time.Duration
+
+	pending bool
+	id      int64
+	sentAt  time.Time
+	// expired fires when the pending challenge has not been answered in time.
+	expired *time.Timer
+}
+
+func newKeepAlive(client *packetConn, protocolVersion int32, timeout time.Duration) *keepAlive {
+	if timeout <= 0 {
+		timeout = defaultKeepAliveTimeout
+	}
+	expired := time.NewTimer(timeout)
+	expired.Stop()
+
+	return &keepAlive{
+		client:          client,
+		protocolVersion: protocolVersion,
+		timeout:         timeout,
+		expired:         expired,
+	}
+}
+
+// send issues a new challenge unless the previous one is still unanswered.
+func (k *keepAlive) send(now time.Time) error {
+	if k.pending {
+		return nil
+	}
+
+	k.id = now.UnixMilli()
+	if err := protocol.SendPlayKeepAlive(k.client, k.protocolVersion, k.id); err != nil {
+		return err
+	}
+	k.pending = true
+	k.sentAt = now
+	k.expired.Reset(k.timeout)
+	return nil
+}
+
+// handle checks a serverbound Keep Alive and returns the round trip time.
+func (k *keepAlive) handle(packet []byte) (time.Duration, error) {
+	id, err := protocol.ReadPlayKeepAlive(packet, k.protocolVersion)
+	if err != nil {
+		return 0, err
+	}
+	if !k.pending || id != k.id {
+		return 0, fmt.Errorf("unexpected keep alive id %d", id)
+	}
+
+	k.pending = false
+	k.expired.Stop()
+	return time.Since(k.sentAt), nil
+}
+
+// isResponse reports whether packet is a serverbound Keep Alive.
+func (k *keepAlive) isResponse(packet []byte) bool {
+	id, _, err := protocol.ReadPacketID(packet)
+	return err == nil && id == protocol.VersionFor(k.protocolVersion).Play.KeepAliveResponse
+}
+
+func (k *keepAlive) stop() {
+	k.expired.Stop()
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+func TestHoldInLimbo_KeepAlive(t *testing.T) {
+	tests := []struct {
+		name string
+		// answer returns the id echoed for a Keep Alive, or false to ignore it.
+		answer     func(id uint64) (uint64, bool)
+		wantReason string
+	}{
+		{
+			name:       "no response",
+			answer:     func(uint64) (uint64, bool) { return 0, false },
+			wantReason: "disconnect.timeout",
+		},
+		{
+			name:       "wrong id",
+			answer:     func(id uint64) (uint64, bool) { return id + 1, true },
+			wantReason: "disconnect.timeout",
+		},
+		{
+			name:       "answered until the hold time runs out",
+			answer:     func(id uint64) (uint64, bool) { return id, true },
+			wantReason: "held",
+		},
+	}
+
+	server := startServer(t, LoginConfig{
+		ErrorMessage:      "held",
+		KeepAliveInterval: 20 * time.Millisecond,
+		KeepAliveTimeout:  100 * time.Millisecond,
+		PlayHoldTime:      500 * time.Millisecond,
+	}, TimeoutConfig{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dialServer(t, server, testProtocol)
+			client.enterPlay(t, "Steve")
+
+			play := protocol.VersionFor(testProtocol).Play
+			for {
+				id, payload := client.read(t)
+				switch id {
+				case play.KeepAlive:
+					echo, ok := tt.answer(binary.BigEndian.Uint64(payload))
+					if ok {
+						client.send(t, binary.BigEndian.AppendUint64([]byte{byte(play.KeepAliveResponse)}, echo))
+					}
+					continue
+				case play.Disconnect:
+					if !bytes.Contains(payload, []byte(tt.wantReason)) {
+						t.Fatalf("expected a disconnect with %q, got %q", tt.wantReason, payload)
+					}
+				default:
+					continue
+				}
+				break
+			}
+		})
+	}
+}
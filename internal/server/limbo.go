@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+// LimboConfig keeps mock logins connected in an empty world instead of
+// disconnecting them, for example while the real server is under maintenance.
+type LimboConfig struct {
+	Enabled bool
+	// Title and Subtitle are shown for as long as the player stays in limbo,
+	// Chat once on join and ActionBar above the hotbar. Empty messages are not
+	// sent.
+	Title     string
+	Subtitle  string
+	Chat      string
+	ActionBar string
+}
+
+const (
+	limboEntityID = 1
+	limboSpawnY   = 300
+	// The client hides an action bar message after about three seconds.
+	limboActionBarInterval = 2 * time.Second
+	limboTitleFadeInTicks  = 10
+	limboTitleStayTicks    = 24 * 60 * 60 * 20
+	limboTitleFadeOutTicks = 20
+)
+
+// holdInLimbo completes the login, spawns the player in the void as a
+// spectator and keeps the connection alive until the client leaves, stops
+// answering Keep Alive, PlayHoldTime runs out, the server shuts down or, with
+// a queue, the player is released. Without Limbo.Enabled no messages are shown.
+func holdInLimbo(ctx context.Context, client *packetConn, protocolVersion int32, playerUUID protocol.UUID, username string, handshake protocol.Handshake, cfg LoginConfig, queue *waitingQueue) {
+	if err := client.SendLoginSuccess(protocolVersion, playerUUID, username); err != nil {
+		log.Println("Failed to send login success:", err)
+		return
+	}
+
+	if protocol.VersionFor(protocolVersion).HasConfigurationState {
+		if !enterConfiguration(client, protocolVersion, cfg.StoreCookies) {
+			return
+		}
+		if err := configureLimbo(client, protocolVersion); err != nil {
+			log.Printf("Failed to configure limbo for username=%q: %v", username, err)
+			return
+		}
+	}
+
+	limbo := cfg.Limbo
+	if !limbo.Enabled {
+		limbo = LimboConfig{}
+	}
+	if queue != nil && cfg.Queue.ActionBar {
+		// The queue position takes the action bar over.
+		limbo.ActionBar = ""
+	}
+	if err := spawnInLimbo(client, protocolVersion, limbo); err != nil {
+		log.Printf("Failed to spawn username=%q in limbo: %v", username, err)
+		return
+	}
+
+	client.conn.enterIdle()
+	log.Printf("Holding username=%q in limbo", username)
+	joinedAt := time.Now()
+
+	var display *queueDisplay
+	var released <-chan struct{}
+	if queue != nil {
+		entry := queue.join(username)
+		defer queue.leave(entry)
+		released = entry.released
+
+		display = newQueueDisplay(client, protocolVersion, cfg.Queue, queue, entry)
+		if err := display.show(); err != nil {
+			log.Printf("Failed to show queue position to username=%q: %v", username, err)
+			return
+		}
+	}
+
+	// The reader hands packets over so that only this goroutine touches the
+	// keep alive state, and drops them once the player is on the way out or
+	// holdInLimbo has returned.
+	packets := make(chan []byte)
+	discard := make(chan struct{})
+	stopReading := sync.OnceFunc(func() { close(discard) })
+	defer stopReading()
+	clientGone := make(chan error, 1)
+	go func() {
+		for {
+			packet, err := client.ReadPacket()
+			if err != nil {
+				clientGone <- err
+				return
+			}
+			select {
+			case packets <- packet:
+			case <-discard:
+			}
+		}
+	}()
+	leave := func() {
+		stopReading()
+		awaitClientGone(client.conn, clientGone)
+	}
+
+	keepAliveInterval := cfg.KeepAliveInterval
+	if keepAliveInterval <= 0 {
+		keepAliveInterval = defaultKeepAliveInterval
+	}
+	keepAliveTicker := time.NewTicker(keepAliveInterval)
+	defer keepAliveTicker.Stop()
+	keepAlive := newKeepAlive(client, protocolVersion, cfg.KeepAliveTimeout)
+	defer keepAlive.stop()
+
+	var holdExpired <-chan time.Time
+	if cfg.PlayHoldTime > 0 {
+		holdTimer := time.NewTimer(cfg.PlayHoldTime)
+		defer holdTimer.Stop()
+		holdExpired = holdTimer.C
+	}
+
+	var refresh <-chan time.Time
+	if limbo.ActionBar != "" || display != nil {
+		refreshTicker := time.NewTicker(limboActionBarInterval)
+		defer refreshTicker.Stop()
+		refresh = refreshTicker.C
+	}
+
+	for {
+		select {
+		case err := <-clientGone:
+			log.Printf("username=%q left limbo after %s: %v", username, time.Since(joinedAt).Round(time.Second), err)
+			return
+		case packet := <-packets:
+			if !keepAlive.isResponse(packet) {
+				continue
+			}
+			if _, err := keepAlive.handle(packet); err != nil {
+				log.Printf("Disconnecting username=%q: %v", username, err)
+				if disconnectFromPlay(client, protocolVersion, keepAliveTimeoutMessage) {
+					leave()
+				}
+				return
+			}
+		case <-released:
+			log.Printf("username=%q released from the queue after %s", username, time.Since(joinedAt).Round(time.Second))
+			if err := display.hide(); err != nil {
+				log.Printf("Failed to hide queue position from username=%q: %v", username, err)
+				return
+			}
+			releaseFromQueue(client, protocolVersion, username, handshake, cfg, queue)
+			leave()
+			return
+		case <-ctx.Done():
+			log.Printf("Disconnecting username=%q: server shutting down", username)
+			if disconnectFromPlay(client, protocolVersion, shutdownMessage(cfg)) {
+				leave()
+			}
+			return
+		case <-holdExpired:
+			log.Printf("Disconnecting username=%q after holding it in play for %s", username, cfg.PlayHoldTime)
+			if disconnectFromPlay(client, protocolVersion, cfg.ErrorMessage) {
+				leave()
+			}
+			return
+		case now := <-keepAliveTicker.C:
+			if err := keepAlive.send(now); err != nil {
+				log.Printf("Failed to send keep alive to username=%q: %v", username, err)
+				return
+			}
+		case <-keepAlive.expired.C:
+			log.Printf("Disconnecting username=%q: %v", username, errKeepAliveTimeout)
+			if disconnectFromPlay(client, protocolVersion, keepAliveTimeoutMessage) {
+				leave()
+			}
+			return
+		case <-refresh:
+			if limbo.ActionBar != "" {
+				if err := protocol.SendSystemChat(client, protocolVersion, limbo.ActionBar, true); err != nil {
+					log.Printf("Failed to send action bar to username=%q: %v", username, err)
+					return
+				}
+			}
+			if display != nil {
+				if err := display.show(); err != nil {
+					log.Printf("Failed to show queue position to username=%q: %v", username, err)
+					return
+				}
+			}
+		}
+	}
+}
+
+// disconnectFromPlay sends a play Disconnect and reports whether it was
+// written.
+func disconnectFromPlay(client *packetConn, protocolVersion int32, message string) bool {
+	if err := protocol.SendPlayDisconnect(client, protocolVersion, message); err != nil {
+		log.Println("Failed to send play disconnect:", err)
+		return false
+	}
+	return true
+}
+
+// awaitClientGone is waitForClientClose for connections whose packets are
+// already being read by another goroutine, which reports on clientGone.
+func awaitClientGone(conn net.Conn, clientGone <-chan error) {
+	closeWrite(conn)
+
+	select {
+	case <-clientGone:
+	case <-time.After(disconnectLingerTimeout):
+	}
+}
+
+// configureLimbo sends the registries of the void world and waits for the
+// client to acknowledge the end of the configuration state.
+func configureLimbo(client *packetConn, protocolVersion int32) error {
+	if err := protocol.SendFeatureFlags(client, protocolVersion); err != nil {
+		return err
+	}
+	if err := protocol.SendRegistryData(client, protocolVersion); err != nil {
+		return err
+	}
+	if err := protocol.SendFinishConfiguration(client, protocolVersion); err != nil {
+		return err
+	}
+
+	// Client Information and the brand plugin message may arrive first.
+	finishAck := protocol.VersionFor(protocolVersion).Configuration.FinishConfigurationAck
+	for {
+		packet, err := client.ReadPacket()
+		if err != nil {
+			return err
+		}
+		if id, _, err := protocol.ReadPacketID(packet); err == nil && id == finishAck {
+			return nil
+		}
+	}
+}
+
+// spawnInLimbo joins the player to the void world and shows the configured
+// messages. Spectators skip the wait for the chunk under them, so no chunk
+// data is sent.
+func spawnInLimbo(client *packetConn, protocolVersion int32, limbo LimboConfig) error {
+	if err := protocol.SendJoinGame(client, protocolVersion, limboEntityID, protocol.GameModeSpectator); err != nil {
+		return err
+	}
+	if err := protocol.SendSetDefaultSpawnPosition(client, protocolVersion, 0, limboSpawnY, 0, 0); err != nil {
+		return err
+	}
+	if err := protocol.SendSyncPlayerPosition(client, protocolVersion, 0.5, limboSpawnY, 0.5, 0, 0, 1); err != nil {
+		return err
+	}
+	if protocol.VersionFor(protocolVersion).WaitsForLevelChunks {
+		if err := protocol.SendGameEvent(client, protocolVersion, protocol.GameEventStartWaitingForChunks, 0); err != nil {
+			return err
+		}
+	}
+
+	if limbo.Title != "" || limbo.Subtitle != "" {
+		if err := protocol.SendTitle(client, protocolVersion, limbo.Title, limbo.Subtitle, limboTitleFadeInTicks, limboTitleStayTicks, limboTitleFadeOutTicks); err != nil {
+			return err
+		}
+	}
+	if limbo.Chat != "" {
+		if err := protocol.SendSystemChat(client, protocolVersion, limbo.Chat, false); err != nil {
+			return err
+		}
+	}
+	if limbo.ActionBar != "" {
+		if err := protocol.SendSystemChat(client, protocolVersion, limbo.ActionBar, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+func TestHoldInLimbo_SpawnsPlayer(t *testing.T) {
+	tests := []struct {
+		name            string
+		protocolVersion int32
+	}{
+		{name: "1.20.1", protocolVersion: 763},
+		{name: "1.20.4 configuration state", protocolVersion: 765},
+		{name: "1.21.4", protocolVersion: 769},
+	}
+
+	limbo := LimboConfig{Enabled: true, Title: "Maintenance", Chat: "Please wait"}
+	server := startServer(t, LoginConfig{Limbo: limbo}, TimeoutConfig{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dialServer(t, server, tt.protocolVersion)
+			client.enterPlay(t, "Steve")
+
+			play := protocol.VersionFor(tt.protocolVersion).Play
+			client.readUntil(t, play.SyncPlayerPosition)
+			if title := client.readUntil(t, play.SetTitleText); !bytes.Contains(title, []byte("Maintenance")) {
+				t.Fatalf("unexpected title %q", title)
+			}
+			if chat := client.readUntil(t, play.SystemChat); !bytes.Contains(chat, []byte("Please wait")) {
+				t.Fatalf("unexpected chat message %q", chat)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+// QueueRelease selects how a player at the front of the queue reaches the
+// real server.
+type QueueRelease string
+
+const (
+	// QueueReleaseTransfer sends 1.20.5+ players to RealServerAddr with the
+	// Transfer packet. Older clients are released like QueueReleaseProxy.
+	QueueReleaseTransfer QueueRelease = "transfer"
+	// QueueReleaseProxy lets the player's next login through the whitelist
+	// proxy. 1.20.5+ players are transferred back to MineMock to reconnect
+	// automatically, older ones are disconnected with ReleaseMessage.
+	QueueReleaseProxy QueueRelease = "proxy"
+)
+
+// QueueConfig turns limbo into a waiting queue that releases players one by
+// one towards RealServerAddr.
+type QueueConfig struct {
+	Enabled bool
+	// Throughput is the number of players released per minute.
+	Throughput int
+	Release    QueueRelease
+	// Message is shown in the action bar and/or boss bar, with {position}
+	// and {size} replaced by the player's position and the queue length.
+	Message        string
+	ActionBar      bool
+	BossBar        bool
+	ReleaseMessage string
+}
+
+// queuePassTTL is how long a player released to the proxy has to reconnect.
+const queuePassTTL = 2 * time.Minute
+
+var queueBossBarID = protocol.OfflinePlayerUUID("minemock:queue")
+
+// queueEntry is a player waiting in the queue. released is closed when the
+// player reaches the front and may leave.
+type queueEntry struct {
+	username string
+	released chan struct{}
+}
+
+// waitingQueue releases queued players in join order at a fixed rate and
+// remembers the players released to the proxy until they reconnect.
+type waitingQueue struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries []*queueEntry
+	passes  map[string]time.Time // lower-case username -> expiry
+}
+
+func newWaitingQueue(throughput int) *waitingQueue {
+	if throughput < 1 {
+		throughput = 1
+	}
+
+	return &waitingQueue{
+		interval: time.Minute / time.Duration(throughput),
+		passes:   map[string]time.Time{},
+	}
+}
+
+// Run releases the player at the front of the queue every interval until ctx
+// is cancelled.
+func (q *waitingQueue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			q.releaseNext()
+			q.prunePasses(now)
+		}
+	}
+}
+
+func (q *waitingQueue) join(username string) *queueEntry {
+	entry := &queueEntry{username: username, released: make(chan struct{})}
+
+	q.mu.Lock()
+	q.entries = append(q.entries, entry)
+	q.mu.Unlock()
+
+	return entry
+}
+
+// leave removes a player that disconnected before being released.
+func (q *waitingQueue) leave(entry *queueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, queued := range q.entries {
+		if queued == entry {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// position returns the 1-based position of entry and the queue length, or 0
+// once the entry has been released.
+func (q *waitingQueue) position(entry *queueEntry) (int, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, queued := range q.entries {
+		if queued == entry {
+			return i + 1, len(q.entries)
+		}
+	}
+
+	return 0, len(q.entries)
+}
+
+func (q *waitingQueue) releaseNext() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.entries) == 0 {
+		return
+	}
+
+	entry := q.entries[0]
+	q.entries = q.entries[1:]
+	close(entry.released)
+}
+
+// grantPass lets username through the proxy on its next login.
+func (q *waitingQueue) grantPass(username string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.passes[strings.ToLower(username)] = time.Now().Add(queuePassTTL)
+}
+
+// usePass reports whether username was released to the proxy and consumes
+// the pass.
+func (q *waitingQueue) usePass(username string) bool {
+	if q == nil {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := strings.ToLower(username)
+	expiry, ok := q.passes[key]
+	if !ok {
+		return false
+	}
+	delete(q.passes, key)
+
+	return time.Now().Before(expiry)
+}
+
+func (q *waitingQueue) prunePasses(now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for username, expiry := range q.passes {
+		if now.After(expiry) {
+			delete(q.passes, username)
+		}
+	}
+}
+
+func queueMessage(message string, position int, size int) string {
+	return strings.NewReplacer(
+		"{position}", strconv.Itoa(position),
+		"{size}", strconv.Itoa(size),
+	).Replace(message)
+}
+
+// queueDisplay shows a limbo player's position in the action bar and/or a
+// boss bar that fills up as the player moves forward.
+type queueDisplay struct {
+	client          *packetConn
+	protocolVersion int32
+	cfg             QueueConfig
+	queue           *waitingQueue
+	entry           *queueEntry
+
+	startPosition int
+	bossBarShown  bool
+}
+
+func newQueueDisplay(client *packetConn, protocolVersion int32, cfg QueueConfig, queue *waitingQueue, entry *queueEntry) *queueDisplay {
+	return &queueDisplay{
+		client:          client,
+		protocolVersion: protocolVersion,
+		cfg:             cfg,
+		queue:           queue,
+		entry:           entry,
+	}
+}
+
+func (d *queueDisplay) show() error {
+	position, size := d.queue.position(d.entry)
+	if position == 0 {
+		return nil
+	}
+	if d.startPosition == 0 {
+		d.startPosition = position
+	}
+
+	message := queueMessage(d.cfg.Message, position, size)
+	if d.cfg.ActionBar {
+		if err := protocol.SendSystemChat(d.client, d.protocolVersion, message, true); err != nil {
+			return err
+		}
+	}
+	if !d.cfg.BossBar {
+		return nil
+	}
+
+	progress := float32(d.startPosition-position+1) / float32(d.startPosition)
+	if d.bossBarShown {
+		return protocol.SendBossBarUpdate(d.client, d.protocolVersion, queueBossBarID, message, progress)
+	}
+	d.bossBarShown = true
+	return protocol.SendBossBarAdd(d.client, d.protocolVersion, queueBossBarID, message, progress, protocol.BossBarColorYellow)
+}
+
+// hide removes the boss bar before the player leaves limbo, so that it is not
+// carried along by a transfer.
+func (d *queueDisplay) hide() error {
+	if !d.bossBarShown {
+		return nil
+	}
+	d.bossBarShown = false
+
+	return protocol.SendBossBarRemove(d.client, d.protocolVersion, queueBossBarID)
+}
+
+// releaseFromQueue sends a player whose turn has come towards the real
+// server, as configured by QueueConfig.Release. The caller waits for the
+// client to close the connection.
+func releaseFromQueue(client *packetConn, protocolVersion int32, username string, handshake protocol.Handshake, cfg LoginConfig, queue *waitingQueue) {
+	if cfg.RealServerAddr == "" {
+		log.Printf("Cannot release username=%q from the queue: no real server address", username)
+		if err := protocol.SendPlayDisconnect(client, protocolVersion, cfg.ErrorMessage); err != nil {
+			log.Println("Failed to send play disconnect:", err)
+		}
+		return
+	}
+
+	supportsTransfer := protocol.VersionFor(protocolVersion).SupportsTransfer
+	if cfg.Queue.Release == QueueReleaseTransfer && supportsTransfer {
+		host, port, err := splitTransferTarget(cfg.RealServerAddr)
+		if err != nil {
+			log.Printf("Invalid real server address %q: %v", cfg.RealServerAddr, err)
+			return
+		}
+		if err := protocol.SendPlayTransfer(client, protocolVersion, host, port); err != nil {
+			log.Println("Failed to send transfer:", err)
+			return
+		}
+		log.Printf("Transferred username=%q -> %s", username, cfg.RealServerAddr)
+		return
+	}
+
+	queue.grantPass(username)
+	if supportsTransfer {
+		// Coming back through a transfer, the next login is proxied without
+		// the player having to do anything.
+		if err := protocol.SendPlayTransfer(client, protocolVersion, handshake.Host, int32(handshake.Port)); err != nil {
+			log.Println("Failed to send transfer:", err)
+			return
+		}
+	} else if err := protocol.SendPlayDisconnect(client, protocolVersion, cfg.Queue.ReleaseMessage); err != nil {
+		log.Println("Failed to send play disconnect:", err)
+		return
+	}
+	log.Printf("username=%q may reconnect to be proxied to %s", username, cfg.RealServerAddr)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+// startBackend stands in for the real server: it reports the handshake of
+// every proxied login and disconnects the player with "backend".
+func startBackend(t *testing.T) (string, <-chan protocol.Handshake) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	handshakes := make(chan protocol.Handshake, 4)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				packet, err := protocol.ReadPacket(conn)
+				if err != nil {
+					return
+				}
+				handshake, err := protocol.ReadHandshake(packet)
+				if err != nil {
+					return
+				}
+				if _, err := protocol.ReadPacket(conn); err != nil {
+					return
+				}
+				handshakes <- handshake
+				_ = protocol.SendLoginDisconnect(conn, "backend")
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), handshakes
+}
+
+func TestHoldInLimbo_QueueRelease(t *testing.T) {
+	tests := []struct {
+		name            string
+		release         QueueRelease
+		protocolVersion int32
+		// transferTo is where the released player is transferred, empty when
+		// it is disconnected with the release message.
+		transferTo string
+		proxied    bool
+		// bossBar shows the queue in a boss bar, which must be removed before
+		// the player is released.
+		bossBar bool
+	}{
+		{name: "transfer", release: QueueReleaseTransfer, protocolVersion: 766, transferTo: "backend", bossBar: true},
+		{name: "proxy after transfer", release: QueueReleaseProxy, protocolVersion: 766, transferTo: "localhost", proxied: true},
+		{name: "proxy after disconnect", release: QueueReleaseProxy, protocolVersion: 763, proxied: true},
+		{name: "transfer unsupported", release: QueueReleaseTransfer, protocolVersion: 763, proxied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendAddr, backendHandshakes := startBackend(t)
+			server := startServer(t, LoginConfig{
+				RealServerAddr: backendAddr,
+				Queue: QueueConfig{
+					Enabled:        true,
+					Throughput:     int(time.Minute / (20 * time.Millisecond)),
+					Release:        tt.release,
+					BossBar:        tt.bossBar,
+					ReleaseMessage: "your turn",
+				},
+			}, TimeoutConfig{})
+
+			client := dialServer(t, server, tt.protocolVersion)
+			client.enterPlay(t, "Steve")
+
+			play := protocol.VersionFor(tt.protocolVersion).Play
+			for removed := !tt.bossBar; !removed; {
+				switch id, payload := client.read(t); id {
+				case play.BossBar:
+					removed = payload[16] == 0x01
+				case play.Transfer, play.Disconnect:
+					t.Fatalf("expected the boss bar to be removed before packet 0x%02X", id)
+				}
+			}
+			switch tt.transferTo {
+			case "":
+				if reason := client.readUntil(t, play.Disconnect); !bytes.Contains(reason, []byte("your turn")) {
+					t.Fatalf("unexpected release disconnect %q", reason)
+				}
+			case "backend":
+				host, _, _ := net.SplitHostPort(backendAddr)
+				if target := client.readUntil(t, play.Transfer); !bytes.Contains(target, []byte(host)) {
+					t.Fatalf("expected a transfer to %s, got %q", backendAddr, target)
+				}
+			default:
+				if target := client.readUntil(t, play.Transfer); !bytes.Contains(target, []byte(tt.transferTo)) {
+					t.Fatalf("expected a transfer to %s, got %q", tt.transferTo, target)
+				}
+			}
+			client.conn.Close()
+
+			if !tt.proxied {
+				return
+			}
+			nextState := protocol.StateLogin
+			if tt.transferTo != "" {
+				nextState = protocol.StateTransfer
+			}
+			reconnected := dialServer(t, server, tt.protocolVersion)
+			reconnected.startLogin(t, nextState, "Steve")
+			if id, reason := reconnected.read(t); id != 0x00 || !bytes.Contains(reason, []byte("backend")) {
+				t.Fatalf("expected the backend to answer, got packet 0x%02X %q", id, reason)
+			}
+
+			select {
+			case handshake := <-backendHandshakes:
+				if handshake.NextState != protocol.StateLogin {
+					t.Fatalf("expected a login handshake on the backend, got next state %d", handshake.NextState)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the backend got no handshake")
+			}
+		})
+	}
+}
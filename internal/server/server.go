@@ -1,94 +1,421 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	"MineMock/internal/protocol"
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+	"github.com/ketchandmayo/MineMock/internal/session"
 )
 
 type StatusConfig struct {
-	MOTD          string
-	VersionName   string
-	Protocol      int32
-	MaxPlayers    int32
-	OnlinePlayers int32
+	MOTD               string
+	VersionName        string
+	Protocol           int32
+	MaxPlayers         int32
+	OnlinePlayers      int32
+	Favicon            string
+	PlayerSample       []protocol.StatusPlayerSample
+	EnforcesSecureChat bool
+	PreviewsChat       bool
 }
 
 type LoginConfig struct {
-	ErrorMessage               string
-	ErrorDelay                 time.Duration
-	ForceConnectionLostTitle   bool
-	RealServerAddr             string
-	IsWhitelisted              func(username string) bool
+	ErrorMessage             string
+	ErrorDelay               time.Duration
+	ForceConnectionLostTitle bool
+	RealServerAddr           string
+	IsWhitelisted            func(username string, playerUUID string) bool
+	UseClientUUID            bool
+	// EnableCompression sends Set Compression in the mock login flow; packets
+	// of at least CompressionThreshold bytes are compressed from then on.
+	EnableCompression    bool
+	CompressionThreshold int32
+	// OnlineMode authenticates mock logins with Encryption Request/Response
+	// and the session server at SessionServerURL.
+	OnlineMode       bool
+	SessionServerURL string
+	// MockSessionServerAddr starts the built-in session server on this address
+	// when it is not empty, answering for MockSessionAccounts.
+	MockSessionServerAddr string
+	MockSessionAccounts   []session.Account
+	// LoginPluginRequests are sent after Login Start in mock logins. With
+	// RequireLoginPluginUnderstood the player is disconnected when the client
+	// does not understand one of the channels.
+	LoginPluginRequests          []protocol.LoginPluginRequest
+	RequireLoginPluginUnderstood bool
+	// TransferTarget returns the host:port a 1.20.5+ player is sent to with the
+	// Transfer packet instead of being disconnected.
+	TransferTarget func(username string, playerUUID string, serverHost string) (string, bool)
+	// CookieRequests are requested from 1.20.5+ clients during login and
+	// StoreCookies are stored on them when they enter the configuration state.
+	CookieRequests []string
+	StoreCookies   []protocol.Cookie
+	// Limbo replaces the disconnect at the end of mock logins. Queue holds
+	// players in limbo too, until they are released to RealServerAddr.
+	Limbo LimboConfig
+	Queue QueueConfig
+	// ShutdownMessage disconnects the players held in play when Run stops.
+	// The vanilla "Server closed" message is used when it is empty.
+	ShutdownMessage string
+	// KeepAliveInterval and KeepAliveTimeout apply to players held in play;
+	// a player that does not echo a Keep Alive within the timeout is
+	// disconnected. PlayHoldTime, when positive, holds mock logins in play
+	// for that long before the final disconnect, and caps the stay in limbo.
+	KeepAliveInterval          time.Duration
+	KeepAliveTimeout           time.Duration
+	PlayHoldTime               time.Duration
 	SimpleVoicechatListenAddr  string
 	SimpleVoicechatBackendAddr string
 }
 
-func Run(addr string, statusCfg StatusConfig, loginCfg LoginConfig) error {
-	voicechatProxy, err := newUDPProxy(loginCfg.SimpleVoicechatListenAddr, loginCfg.SimpleVoicechatBackendAddr)
+// Server is a MineMock server listening on a TCP address. Listen starts it
+// and Serve handles its connections until Shutdown; Run does both.
+type Server struct {
+	statusCfg StatusConfig
+	loginCfg  LoginConfig
+	timeouts  TimeoutConfig
+	hooks     Hooks
+
+	listener       net.Listener
+	authenticator  *loginAuthenticator
+	queue          *waitingQueue
+	voicechatProxy *udpProxy
+	sessionServer  *http.Server
+	connections    *connectionSet
+	acceptFailures atomic.Uint64
+	timedOut       timeoutCounters
+
+	// ctx is cancelled by Shutdown, which tells the connection handlers to
+	// let their players go.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Run serves until ctx is cancelled. It then stops accepting connections,
+// disconnects the players held in play with ShutdownMessage and waits up to
+// timeouts.Drain for the other connections, proxied sessions included, to
+// end before closing them.
+func Run(ctx context.Context, addr string, statusCfg StatusConfig, loginCfg LoginConfig, timeouts TimeoutConfig) error {
+	server, err := Listen(addr, statusCfg, loginCfg, timeouts, Hooks{})
+	if err != nil {
+		return err
+	}
+
+	stop := context.AfterFunc(ctx, server.Shutdown)
+	defer stop()
+
+	return server.Serve()
+}
+
+// Listen starts the listener and the services the configuration asks for.
+// Port 0 in addr picks a free port, see Addr.
+func Listen(addr string, statusCfg StatusConfig, loginCfg LoginConfig, timeouts TimeoutConfig, hooks Hooks) (*Server, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &Server{
+		statusCfg:   statusCfg,
+		loginCfg:    loginCfg,
+		timeouts:    timeouts,
+		hooks:       hooks,
+		connections: newConnectionSet(),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	if err := server.start(addr); err != nil {
+		server.close()
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func (s *Server) start(addr string) error {
+	voicechatProxy, err := newUDPProxy(s.loginCfg.SimpleVoicechatListenAddr, s.loginCfg.SimpleVoicechatBackendAddr)
 	if err != nil {
 		return fmt.Errorf("start UDP voice chat proxy: %w", err)
 	}
 	if voicechatProxy != nil {
-		defer voicechatProxy.Close()
+		s.voicechatProxy = voicechatProxy
 		go voicechatProxy.Run()
 	} else {
 		log.Println("UDP voice chat proxy disabled")
 	}
 
-	listener, err := net.Listen("tcp", addr)
+	if s.loginCfg.MockSessionServerAddr != "" {
+		s.sessionServer, err = startMockSessionServer(s.loginCfg.MockSessionServerAddr, s.loginCfg.MockSessionAccounts)
+		if err != nil {
+			return fmt.Errorf("start mock session server: %w", err)
+		}
+	}
+
+	if s.loginCfg.OnlineMode {
+		s.authenticator, err = newLoginAuthenticator(s.loginCfg.SessionServerURL)
+		if err != nil {
+			return fmt.Errorf("start online mode: %w", err)
+		}
+		log.Println("Online mode enabled, session server: " + s.loginCfg.SessionServerURL)
+	}
+
+	if s.loginCfg.Queue.Enabled {
+		s.queue = newWaitingQueue(s.loginCfg.Queue.Throughput)
+		go s.queue.Run(s.ctx)
+	}
+
+	s.listener, err = net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("start server: %w", err)
 	}
-	defer listener.Close()
 
-	log.Println("Listening on " + addr)
+	log.Println("Listening on " + s.listener.Addr().String())
+	return nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
 
+// Serve accepts connections until Shutdown is called or the listener fails,
+// then drains them as described on Run and stops the other services.
+// Temporary Accept errors are retried with exponential backoff.
+func (s *Server) Serve() error {
+	defer s.close()
+
+	var serveErr error
+	var acceptDelay time.Duration
 	for {
-		conn, err := listener.Accept()
+		conn, err := s.listener.Accept()
 		if err != nil {
-			log.Println("Connection error:", err)
+			if s.ctx.Err() != nil {
+				break
+			}
+			if errors.Is(err, net.ErrClosed) {
+				log.Println("Listener closed")
+				break
+			}
+
+			failures := s.acceptFailures.Add(1)
+			if !isRetryableAcceptError(err) {
+				serveErr = fmt.Errorf("accept connection: %w", err)
+				break
+			}
+
+			// Out of file descriptors for example: give the open
+			// connections time to end instead of spinning on Accept.
+			acceptDelay = min(max(2*acceptDelay, acceptBackoffMin), acceptBackoffMax)
+			log.Printf("Accept error: %v; retrying in %s (%d accept failures)", err, acceptDelay, failures)
+			select {
+			case <-time.After(acceptDelay):
+			case <-s.ctx.Done():
+			}
 			continue
 		}
+		acceptDelay = 0
+
+		client := newTimeoutConn(conn, s.timeouts, &s.timedOut)
+		s.connections.add(client)
+		go func() {
+			defer s.connections.remove(client)
+			s.handleConnection(client)
+		}()
+	}
+
+	log.Printf("Shutting down, waiting up to %s for %d open connections", s.timeouts.Drain, s.connections.len())
+	if closed := s.connections.drain(s.timeouts.Drain); closed > 0 {
+		log.Printf("Closed %d connections still open after %s", closed, s.timeouts.Drain)
+	}
+
+	timedOut := s.TimedOutConnections()
+	log.Printf(
+		"Accept failures: %d; timed out connections: handshake=%d status=%d login=%d idle=%d",
+		s.AcceptFailures(),
+		timedOut.Handshake,
+		timedOut.Status,
+		timedOut.Login,
+		timedOut.Idle,
+	)
+	return serveErr
+}
+
+// isRetryableAcceptError reports whether Accept may succeed later, like when
+// the process is out of file descriptors or a client reset its connection
+// before it was accepted.
+func isRetryableAcceptError(err error) bool {
+	if errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) || errors.Is(err, syscall.ECONNABORTED) {
+		return true
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		return false
+	}
+	// Temporary is deprecated, but still what other listener implementations
+	// report for their own transient errors.
+	return netErr.Timeout() || netErr.Temporary()
+}
+
+// AcceptFailures returns the number of failed Accept calls on the listener,
+// not counting the one that ends Serve on Shutdown.
+func (s *Server) AcceptFailures() uint64 {
+	return s.acceptFailures.Load()
+}
+
+// TimedOutConnections returns how many connections timed out in each phase.
+func (s *Server) TimedOutConnections() TimeoutCounts {
+	return s.timedOut.counts()
+}
 
-		go handleConnection(conn, statusCfg, loginCfg, voicechatProxy)
+// Shutdown stops accepting connections and makes Serve return once the open
+// ones are drained.
+func (s *Server) Shutdown() {
+	s.cancel()
+	if s.listener != nil {
+		_ = s.listener.Close()
 	}
 }
 
-func handleConnection(conn net.Conn, statusCfg StatusConfig, loginCfg LoginConfig, voicechatProxy *udpProxy) {
+func (s *Server) close() {
+	s.Shutdown()
+	if s.voicechatProxy != nil {
+		s.voicechatProxy.Close()
+	}
+	if s.sessionServer != nil {
+		_ = s.sessionServer.Close()
+	}
+}
+
+func startMockSessionServer(addr string, accounts []session.Account) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	httpServer := &http.Server{
+		Handler:           session.NewServer(accounts),
+		ReadHeaderTimeout: sessionServerTimeout,
+	}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("Mock session server error:", err)
+		}
+	}()
+
+	log.Printf("Mock session server listening on %s with %d accounts", listener.Addr(), len(accounts))
+	return httpServer, nil
+}
+
+func (s *Server) handleConnection(conn *timeoutConn) {
+	statusCfg, loginCfg := s.statusCfg, s.loginCfg
+	connectedAt := time.Now()
+	defer s.hooks.connectionClosed(conn, connectedAt)
 	defer conn.Close()
+	defer conn.reportTimeout()
 	log.Println("New connection from", conn.RemoteAddr())
 
-	handshakePacket, err := protocol.ReadPacket(conn)
+	var firstByte [1]byte
+	if _, err := io.ReadFull(conn, firstByte[:]); err != nil {
+		log.Println("Failed to read handshake:", err)
+		return
+	}
+	if firstByte[0] == protocol.LegacyPingPacketID {
+		handleLegacyPing(conn, statusCfg)
+		return
+	}
+
+	handshakeReader := protocol.NewPacketReader(io.MultiReader(bytes.NewReader(firstByte[:]), conn), protocol.MaxHandshakePacketLength)
+	handshakePacket, err := handshakeReader.ReadPacket()
 	if err != nil {
 		log.Println("Failed to read handshake:", err)
 		return
 	}
 
-	nextState, err := protocol.ReadHandshakeNextState(handshakePacket)
+	handshake, err := protocol.ReadHandshake(handshakePacket)
 	if err != nil {
 		log.Println("Failed to parse handshake:", err)
 		return
 	}
+	logHandshake(conn, handshake)
+	s.hooks.handshake(conn, handshake)
 
-	switch nextState {
-	case 1:
+	switch handshake.NextState {
+	case protocol.StateStatus:
+		conn.enterStatus()
 		handleStatus(conn, statusCfg)
-	case 2:
-		handleLogin(conn, handshakePacket, loginCfg, voicechatProxy)
+	case protocol.StateLogin, protocol.StateTransfer:
+		conn.enterLogin(loginCfg.ErrorDelay)
+		s.handleLogin(conn, handshakePacket, handshake)
 	default:
-		log.Println("Unsupported next state:", nextState)
+		log.Println("Unsupported next state:", handshake.NextState)
+	}
+}
+
+func logHandshake(conn net.Conn, handshake protocol.Handshake) {
+	details := ""
+	if handshake.IsForge() {
+		details += " forge=" + handshake.ForgeMarker
+	}
+	if handshake.BungeeCord != nil {
+		details += fmt.Sprintf(" bungeecord_ip=%s bungeecord_uuid=%s", handshake.BungeeCord.ClientIP, handshake.BungeeCord.UUID)
+	}
+
+	log.Printf(
+		"Handshake from %s: protocol=%d address=%s:%d next_state=%d%s",
+		conn.RemoteAddr(),
+		handshake.ProtocolVersion,
+		handshake.Host,
+		handshake.Port,
+		handshake.NextState,
+		details,
+	)
+}
+
+func handleLegacyPing(conn net.Conn, statusCfg StatusConfig) {
+	_ = conn.SetReadDeadline(time.Now().Add(legacyPingReadTimeout))
+	ping, err := protocol.ReadLegacyPing(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		log.Println("Failed to read legacy ping:", err)
+		return
+	}
+
+	if ping.Format == protocol.LegacyPing1_6 {
+		log.Printf("Legacy ping from %s: protocol=%d address=%s:%d", conn.RemoteAddr(), ping.ProtocolVersion, ping.Host, ping.Port)
+	} else {
+		log.Printf("Legacy ping from %s: format=%s", conn.RemoteAddr(), ping.Format)
+	}
+
+	status := protocol.LegacyStatus{
+		Protocol:      statusCfg.Protocol,
+		VersionName:   statusCfg.VersionName,
+		MOTD:          protocol.ToLegacyText(statusCfg.MOTD),
+		OnlinePlayers: statusCfg.OnlinePlayers,
+		MaxPlayers:    statusCfg.MaxPlayers,
+	}
+	if err := protocol.SendLegacyKick(conn, ping.Format, status); err != nil {
+		log.Println("Failed to send legacy ping response:", err)
 	}
 }
 
 func handleStatus(conn net.Conn, statusCfg StatusConfig) {
-	requestPacket, err := protocol.ReadPacket(conn)
+	reader := protocol.NewPacketReader(conn, protocol.MaxStatusPacketLength)
+	requestPacket, err := reader.ReadPacket()
 	if err != nil {
 		log.Println("Failed to read status request:", err)
 		return
@@ -100,12 +427,18 @@ func handleStatus(conn net.Conn, statusCfg StatusConfig) {
 		return
 	}
 
-	if err := protocol.SendStatusResponse(conn, statusCfg.VersionName, statusCfg.Protocol, statusCfg.MOTD, statusCfg.MaxPlayers, statusCfg.OnlinePlayers); err != nil {
+	status, err := buildStatusResponse(statusCfg)
+	if err != nil {
+		log.Println("Failed to build status response:", err)
+		return
+	}
+
+	if err := protocol.SendStatusResponse(conn, status); err != nil {
 		log.Println("Failed to send status response:", err)
 		return
 	}
 
-	pingPacket, err := protocol.ReadPacket(conn)
+	pingPacket, err := reader.ReadPacket()
 	if err != nil {
 		log.Println("Failed to read ping request:", err)
 		return
@@ -122,31 +455,91 @@ func handleStatus(conn net.Conn, statusCfg StatusConfig) {
 	}
 }
 
-func handleLogin(conn net.Conn, handshakePacket []byte, cfg LoginConfig, voicechatProxy *udpProxy) {
-	loginStartPacket, err := protocol.ReadPacket(conn)
+func buildStatusResponse(statusCfg StatusConfig) (protocol.StatusResponse, error) {
+	description, err := protocol.StatusDescription(statusCfg.MOTD)
+	if err != nil {
+		return protocol.StatusResponse{}, fmt.Errorf("encode description: %w", err)
+	}
+
+	return protocol.StatusResponse{
+		Version: protocol.StatusVersion{
+			Name:     statusCfg.VersionName,
+			Protocol: statusCfg.Protocol,
+		},
+		Players: protocol.StatusPlayers{
+			Max:    statusCfg.MaxPlayers,
+			Online: statusCfg.OnlinePlayers,
+			Sample: statusCfg.PlayerSample,
+		},
+		Description:        description,
+		Favicon:            statusCfg.Favicon,
+		EnforcesSecureChat: statusCfg.EnforcesSecureChat,
+		PreviewsChat:       statusCfg.PreviewsChat,
+	}, nil
+}
+
+func (s *Server) handleLogin(conn *timeoutConn, handshakePacket []byte, handshake protocol.Handshake) {
+	ctx, cfg, authenticator, queue, voicechatProxy := s.ctx, s.loginCfg, s.authenticator, s.queue, s.voicechatProxy
+
+	loginStartPacket, err := protocol.NewPacketReader(conn, protocol.MaxLoginPacketLength).ReadPacket()
 	if err != nil {
 		log.Println("Failed to read login start:", err)
 		return
 	}
 
-	username, err := protocol.ReadLoginStartUsername(loginStartPacket)
+	loginStart, err := protocol.ReadLoginStart(loginStartPacket, handshake.ProtocolVersion)
 	if err != nil {
-		log.Println("Failed to parse login start username:", err)
+		log.Println("Failed to parse login start:", err)
 		return
 	}
+	username := loginStart.Username
 
 	remoteIP := conn.RemoteAddr().String()
 	if host, _, err := net.SplitHostPort(remoteIP); err == nil {
 		remoteIP = host
 	}
-	log.Printf("Login attempt: username=%q ip=%s", username, remoteIP)
 
-	if shouldProxyPlayer(username, cfg) {
+	clientUUID := ""
+	if loginStart.HasUUID {
+		clientUUID = loginStart.UUID.String()
+	}
+	playerUUID := loginUUID(loginStart, cfg)
+	log.Printf(
+		"Login attempt: username=%q uuid=%s client_uuid=%s signed=%t ip=%s",
+		username,
+		playerUUID,
+		valueOrPlaceholder(clientUUID, "<none>"),
+		loginStart.Signature != nil,
+		remoteIP,
+	)
+
+	releasedFromQueue := cfg.RealServerAddr != "" && queue.usePass(username)
+	if releasedFromQueue {
+		log.Printf("username=%q was released from the queue, proxying", username)
+		// The real server may not accept transfers, which is how 1.20.5+
+		// players released from the queue come back.
+		handshakePacket = loginHandshakePacket(handshakePacket, handshake)
+	}
+
+	proxied := releasedFromQueue || shouldProxyPlayer(username, clientUUID, cfg)
+	s.hooks.login(conn, LoginEvent{
+		Username:        username,
+		UUID:            playerUUID,
+		ClientUUID:      clientUUID,
+		ProtocolVersion: handshake.ProtocolVersion,
+		Proxied:         proxied,
+	})
+
+	if proxied {
 		if voicechatProxy != nil {
 			voicechatProxy.AuthorizeIP(remoteIP)
 		}
+		conn.enterIdle()
 		if err := proxyToRealServer(conn, cfg.RealServerAddr, handshakePacket, loginStartPacket, username); err != nil {
 			log.Printf("Proxy error for %q: %v", username, err)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				return
+			}
 			if sendErr := protocol.SendLoginDisconnect(conn, cfg.ErrorMessage); sendErr != nil {
 				log.Println("Failed to send disconnect after proxy error:", sendErr)
 			}
@@ -154,33 +547,507 @@ func handleLogin(conn net.Conn, handshakePacket []byte, cfg LoginConfig, voicech
 		return
 	}
 
+	client := newPacketConn(conn)
+	if authenticator != nil {
+		profile, err := authenticator.authenticate(client, handshake.ProtocolVersion, username)
+		if err != nil {
+			log.Printf("Failed to verify username=%q: %v", username, err)
+			if errors.Is(err, errKeyExchange) {
+				return
+			}
+			if sendErr := protocol.SendLoginDisconnect(client, verificationFailureMessage(err)); sendErr != nil {
+				log.Println("Failed to send disconnect:", sendErr)
+			}
+			return
+		}
+
+		username = profile.Name
+		if parsed, err := protocol.ParseUUID(profile.ID); err == nil {
+			playerUUID = parsed
+		}
+		log.Printf("Verified username=%q uuid=%s", username, playerUUID)
+	}
+
+	if cfg.EnableCompression {
+		if err := client.EnableCompression(cfg.CompressionThreshold); err != nil {
+			log.Println("Failed to send set compression:", err)
+			return
+		}
+	}
+
+	if len(cfg.LoginPluginRequests) > 0 {
+		if err := exchangeLoginPluginMessages(client, username, cfg.LoginPluginRequests, cfg.RequireLoginPluginUnderstood); err != nil {
+			log.Printf("Login plugin negotiation failed for username=%q: %v", username, err)
+			if sendErr := protocol.SendLoginDisconnect(client, cfg.ErrorMessage); sendErr != nil {
+				log.Println("Failed to send disconnect:", sendErr)
+			}
+			return
+		}
+	}
+
+	protocolVersion := handshake.ProtocolVersion
+	if len(cfg.CookieRequests) > 0 && protocol.VersionFor(protocolVersion).SupportsCookies {
+		transferred := handshake.NextState == protocol.StateTransfer
+		if err := requestCookies(client, protocolVersion, username, transferred, cfg.CookieRequests); err != nil {
+			log.Printf("Cookie request failed for username=%q: %v", username, err)
+			return
+		}
+	}
+
 	if cfg.ErrorDelay > 0 {
-		time.Sleep(cfg.ErrorDelay)
+		delay := time.NewTimer(cfg.ErrorDelay)
+		select {
+		case <-delay.C:
+		case <-ctx.Done():
+			delay.Stop()
+			if err := protocol.SendLoginDisconnect(client, shutdownMessage(cfg)); err != nil {
+				log.Println("Failed to send disconnect:", err)
+			}
+			return
+		}
+	}
+
+	if target, ok := transferTarget(username, playerUUID, handshake, cfg); ok {
+		if protocol.VersionFor(protocolVersion).SupportsTransfer {
+			transferPlayer(client, protocolVersion, playerUUID, username, target, cfg.StoreCookies)
+			return
+		}
+		log.Printf("Transfer rule for username=%q ignored: protocol %d does not support transfers", username, protocolVersion)
+	}
+
+	if cfg.Limbo.Enabled || cfg.Queue.Enabled || cfg.PlayHoldTime > 0 {
+		warnUnknownProtocol(protocolVersion, username)
+		holdInLimbo(ctx, client, protocolVersion, playerUUID, username, handshake, cfg, queue)
+		return
 	}
 
 	if !cfg.ForceConnectionLostTitle {
-		if err := protocol.SendLoginDisconnect(conn, cfg.ErrorMessage); err != nil {
+		if err := protocol.SendLoginDisconnect(client, cfg.ErrorMessage); err != nil {
 			log.Println("Failed to send disconnect:", err)
 		}
 		return
 	}
 
-	if err := protocol.SendLoginSuccess(conn, username); err != nil {
+	warnUnknownProtocol(protocolVersion, username)
+
+	if err := client.SendLoginSuccess(protocolVersion, playerUUID, username); err != nil {
 		log.Println("Failed to send login success:", err)
 		return
 	}
 
-	if err := protocol.SendPlayDisconnect(conn, cfg.ErrorMessage); err != nil {
+	if protocol.VersionFor(protocolVersion).HasConfigurationState {
+		sendConfigurationDisconnect(client, protocolVersion, cfg.ErrorMessage, cfg.StoreCookies)
+		return
+	}
+
+	if err := protocol.SendPlayDisconnect(client, protocolVersion, cfg.ErrorMessage); err != nil {
 		log.Println("Failed to send play disconnect:", err)
+		return
 	}
+	waitForClientClose(conn)
 }
 
-func shouldProxyPlayer(username string, cfg LoginConfig) bool {
+func warnUnknownProtocol(protocolVersion int32, username string) {
+	if _, ok := protocol.LookupVersion(protocolVersion); !ok {
+		log.Printf("Unknown protocol %d for username=%q, using packet layout of protocol %d", protocolVersion, username, protocol.VersionFor(protocolVersion).Protocol)
+	}
+}
+
+// exchangeLoginPluginMessages sends every Login Plugin Request up front, like
+// a proxy negotiating forwarding, then logs the response to each of them.
+func exchangeLoginPluginMessages(client *packetConn, username string, requests []protocol.LoginPluginRequest, requireUnderstood bool) error {
+	pending := make(map[int32]protocol.LoginPluginRequest, len(requests))
+	for _, request := range requests {
+		if err := protocol.SendLoginPluginRequest(client, request); err != nil {
+			return fmt.Errorf("send login plugin request %q: %w", request.Channel, err)
+		}
+		pending[request.MessageID] = request
+	}
+
+	for len(pending) > 0 {
+		packet, err := client.ReadPacket()
+		if err != nil {
+			return fmt.Errorf("read login plugin response: %w", err)
+		}
+		response, err := protocol.ReadLoginPluginResponse(packet)
+		if err != nil {
+			return err
+		}
+
+		request, ok := pending[response.MessageID]
+		if !ok {
+			return fmt.Errorf("login plugin response for unknown message id %d", response.MessageID)
+		}
+		delete(pending, response.MessageID)
+
+		log.Printf(
+			"Login plugin response: username=%q channel=%s understood=%t data_len=%d data=%s",
+			username,
+			request.Channel,
+			response.Understood,
+			len(response.Data),
+			hexPreview(response.Data),
+		)
+		if requireUnderstood && !response.Understood {
+			return fmt.Errorf("client did not understand channel %s", request.Channel)
+		}
+	}
+
+	return nil
+}
+
+// hexPreview formats binary payloads for logs, truncating long ones.
+func hexPreview(data []byte) string {
+	if len(data) == 0 {
+		return "<empty>"
+	}
+	if len(data) > maxLoggedPayloadBytes {
+		return fmt.Sprintf("%x...", data[:maxLoggedPayloadBytes])
+	}
+
+	return fmt.Sprintf("%x", data)
+}
+
+// sendConfigurationDisconnect waits for Login Acknowledged and disconnects the
+// client from the configuration state, which shows the same "Connection Lost"
+// screen as a play disconnect without requiring registry data.
+func sendConfigurationDisconnect(client *packetConn, protocolVersion int32, message string, cookies []protocol.Cookie) {
+	if !enterConfiguration(client, protocolVersion, cookies) {
+		return
+	}
+
+	if err := protocol.SendConfigurationDisconnect(client, protocolVersion, message); err != nil {
+		log.Println("Failed to send configuration disconnect:", err)
+		return
+	}
+	waitForClientClose(client.conn)
+}
+
+// enterConfiguration waits for Login Acknowledged and stores the configured
+// cookies on clients that support them.
+func enterConfiguration(client *packetConn, protocolVersion int32, cookies []protocol.Cookie) bool {
+	if !awaitLoginAcknowledged(client, protocolVersion) {
+		return false
+	}
+	if !protocol.VersionFor(protocolVersion).SupportsCookies {
+		return true
+	}
+
+	for _, cookie := range cookies {
+		if err := protocol.SendConfigurationStoreCookie(client, protocolVersion, cookie); err != nil {
+			log.Printf("Failed to store cookie %s: %v", cookie.Key, err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestCookies asks the client for every key and logs what it returned,
+// which shows the cookies a transferring server stored on the client.
+func requestCookies(client *packetConn, protocolVersion int32, username string, transferred bool, keys []string) error {
+	for _, key := range keys {
+		if err := protocol.SendLoginCookieRequest(client, protocolVersion, key); err != nil {
+			return fmt.Errorf("send cookie request %s: %w", key, err)
+		}
+	}
+
+	for range keys {
+		packet, err := client.ReadPacket()
+		if err != nil {
+			return fmt.Errorf("read cookie response: %w", err)
+		}
+		response, err := protocol.ReadLoginCookieResponse(packet, protocolVersion)
+		if err != nil {
+			return err
+		}
+
+		payload := "<none>"
+		if response.HasPayload {
+			payload = fmt.Sprintf("%q", response.Payload)
+		}
+		log.Printf("Cookie response: username=%q key=%s transferred=%t payload=%s", username, response.Key, transferred, payload)
+	}
+
+	return nil
+}
+
+func awaitLoginAcknowledged(client *packetConn, protocolVersion int32) bool {
+	ackPacket, err := client.ReadPacket()
+	if err != nil {
+		log.Println("Failed to read login acknowledged:", err)
+		return false
+	}
+	if err := protocol.ReadLoginAcknowledged(ackPacket, protocolVersion); err != nil {
+		log.Println("Invalid login acknowledged packet:", err)
+		return false
+	}
+
+	return true
+}
+
+func transferTarget(username string, playerUUID protocol.UUID, handshake protocol.Handshake, cfg LoginConfig) (string, bool) {
+	if cfg.TransferTarget == nil {
+		return "", false
+	}
+
+	return cfg.TransferTarget(username, playerUUID.String(), handshake.Host)
+}
+
+// transferPlayer completes the login and sends the client to target from the
+// configuration state, where every client that supports transfers arrives.
+func transferPlayer(client *packetConn, protocolVersion int32, playerUUID protocol.UUID, username string, target string, cookies []protocol.Cookie) {
+	host, port, err := splitTransferTarget(target)
+	if err != nil {
+		log.Printf("Invalid transfer target %q for username=%q: %v", target, username, err)
+		return
+	}
+
+	if err := client.SendLoginSuccess(protocolVersion, playerUUID, username); err != nil {
+		log.Println("Failed to send login success:", err)
+		return
+	}
+	if !enterConfiguration(client, protocolVersion, cookies) {
+		return
+	}
+
+	if err := protocol.SendConfigurationTransfer(client, protocolVersion, host, port); err != nil {
+		log.Println("Failed to send transfer:", err)
+		return
+	}
+	log.Printf("Transferred username=%q -> %s", username, target)
+	waitForClientClose(client.conn)
+}
+
+func splitTransferTarget(target string) (string, int32, error) {
+	host, rawPort, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.ParseUint(rawPort, 10, 16)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return host, int32(port), nil
+}
+
+// loginHandshakePacket rewrites a transfer handshake into a login handshake.
+// The next state is the last field and always fits in one byte.
+func loginHandshakePacket(packet []byte, handshake protocol.Handshake) []byte {
+	if handshake.NextState != protocol.StateTransfer || len(packet) == 0 {
+		return packet
+	}
+
+	rewritten := bytes.Clone(packet)
+	rewritten[len(rewritten)-1] = byte(protocol.StateLogin)
+	return rewritten
+}
+
+// packetConn reads and writes packets on a client connection, switching to
+// encrypted streams after Encryption Response and to the compressed framing
+// once Set Compression has been sent.
+type packetConn struct {
+	conn         *timeoutConn
+	reader       *protocol.PacketReader
+	streamWriter io.Writer
+	writer       io.Writer
+}
+
+func newPacketConn(conn *timeoutConn) *packetConn {
+	return &packetConn{
+		conn:         conn,
+		reader:       protocol.NewPacketReader(conn, protocol.MaxLoginPacketLength),
+		streamWriter: conn,
+		writer:       conn,
+	}
+}
+
+// EnableEncryption encrypts both directions with the shared secret. It must
+// be called before EnableCompression, like on a vanilla server.
+func (c *packetConn) EnableEncryption(sharedSecret []byte) error {
+	encrypt, decrypt, err := protocol.NewEncryptionStreams(sharedSecret)
+	if err != nil {
+		return err
+	}
+
+	c.reader.Reset(cipher.StreamReader{S: decrypt, R: c.conn})
+	c.streamWriter = cipher.StreamWriter{S: encrypt, W: c.conn}
+	c.writer = c.streamWriter
+	return nil
+}
+
+// EnableCompression sends Set Compression and compresses every later packet.
+func (c *packetConn) EnableCompression(threshold int32) error {
+	if err := protocol.SendSetCompression(c.writer, threshold); err != nil {
+		return err
+	}
+
+	c.reader.EnableCompression(threshold)
+	c.writer = protocol.NewCompressedWriter(c.streamWriter, threshold)
+	return nil
+}
+
+// SendLoginSuccess ends the login and lifts the packet limit to the one of
+// the configuration and play states.
+func (c *packetConn) SendLoginSuccess(protocolVersion int32, playerUUID protocol.UUID, username string) error {
+	if err := protocol.SendLoginSuccess(c, protocolVersion, playerUUID, username); err != nil {
+		return err
+	}
+
+	c.reader.SetMaxLength(protocol.MaxPacketLength)
+	return nil
+}
+
+func (c *packetConn) ReadPacket() ([]byte, error) {
+	return c.reader.ReadPacket()
+}
+
+func (c *packetConn) Write(p []byte) (int, error) {
+	return c.writer.Write(p)
+}
+
+// waitForClientClose half-closes the connection and discards whatever the
+// client still sends, so unread packets do not turn our close into a reset
+// that drops the disconnect packet on the client side.
+func waitForClientClose(conn net.Conn) {
+	closeWrite(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(disconnectLingerTimeout))
+	_, _ = io.Copy(io.Discard, conn)
+}
+
+// loginAuthenticator performs the online-mode part of the login: the key
+// exchange with the client and the hasJoined check against the session server.
+type loginAuthenticator struct {
+	privateKey *rsa.PrivateKey
+	publicKey  []byte
+	sessions   *session.Client
+}
+
+// errSessionServer marks failures to reach the session server, which vanilla
+// reports differently from a player the session server does not know.
+var errSessionServer = errors.New("session server unavailable")
+
+// errKeyExchange marks an Encryption Response we could not take a shared
+// secret from. The client already encrypts, so the connection is just closed.
+var errKeyExchange = errors.New("key exchange failed")
+
+func newLoginAuthenticator(sessionServerURL string) (*loginAuthenticator, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, serverKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("generate server key: %w", err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("encode server key: %w", err)
+	}
+
+	return &loginAuthenticator{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		sessions:   session.NewClient(sessionServerURL),
+	}, nil
+}
+
+// authenticate sends Encryption Request, enables encryption on the client
+// connection and asks the session server whether the player has joined.
+// Encryption is enabled as soon as the shared secret is known, so that the
+// disconnect for a wrong verify token reaches the client encrypted like it
+// expects.
+func (a *loginAuthenticator) authenticate(client *packetConn, protocolVersion int32, username string) (session.Profile, error) {
+	verifyToken := make([]byte, verifyTokenLength)
+	if _, err := rand.Read(verifyToken); err != nil {
+		return session.Profile{}, fmt.Errorf("generate verify token: %w", err)
+	}
+
+	request := protocol.EncryptionRequest{
+		PublicKey:          a.publicKey,
+		VerifyToken:        verifyToken,
+		ShouldAuthenticate: true,
+	}
+	if err := protocol.SendEncryptionRequest(client, protocolVersion, request); err != nil {
+		return session.Profile{}, fmt.Errorf("send encryption request: %w", err)
+	}
+
+	responsePacket, err := client.ReadPacket()
+	if err != nil {
+		return session.Profile{}, fmt.Errorf("%w: read encryption response: %w", errKeyExchange, err)
+	}
+	response, err := protocol.ReadEncryptionResponse(responsePacket, protocolVersion)
+	if err != nil {
+		return session.Profile{}, fmt.Errorf("%w: %w", errKeyExchange, err)
+	}
+
+	sharedSecret, err := rsa.DecryptPKCS1v15(rand.Reader, a.privateKey, response.SharedSecret)
+	if err != nil {
+		return session.Profile{}, fmt.Errorf("%w: decrypt shared secret: %w", errKeyExchange, err)
+	}
+	if len(sharedSecret) != sharedSecretLength {
+		return session.Profile{}, fmt.Errorf("%w: invalid shared secret length: %d", errKeyExchange, len(sharedSecret))
+	}
+	if err := client.EnableEncryption(sharedSecret); err != nil {
+		return session.Profile{}, fmt.Errorf("%w: enable encryption: %w", errKeyExchange, err)
+	}
+
+	if response.VerifyToken == nil {
+		return session.Profile{}, fmt.Errorf("signed verify tokens are not supported")
+	}
+	decryptedToken, err := rsa.DecryptPKCS1v15(rand.Reader, a.privateKey, response.VerifyToken)
+	if err != nil {
+		return session.Profile{}, fmt.Errorf("decrypt verify token: %w", err)
+	}
+	if !bytes.Equal(decryptedToken, verifyToken) {
+		return session.Profile{}, fmt.Errorf("verify token mismatch")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionServerTimeout)
+	defer cancel()
+
+	serverHash := protocol.MinecraftServerHash("", sharedSecret, a.publicKey)
+	profile, err := a.sessions.HasJoined(ctx, username, serverHash, "")
+	if err != nil && !errors.Is(err, session.ErrNotVerified) {
+		return session.Profile{}, fmt.Errorf("%w: %w", errSessionServer, err)
+	}
+
+	return profile, err
+}
+
+// verificationFailureMessage returns the vanilla disconnect reason for a failed
+// online-mode login.
+func verificationFailureMessage(err error) string {
+	if errors.Is(err, errSessionServer) {
+		return `{"translate":"multiplayer.disconnect.authservers_down"}`
+	}
+
+	return `{"translate":"multiplayer.disconnect.unverified_username"}`
+}
+
+// loginUUID returns the vanilla offline-mode UUID for the player, or the UUID
+// the client reported in Login Start when UseClientUUID is enabled.
+func loginUUID(loginStart protocol.LoginStart, cfg LoginConfig) protocol.UUID {
+	if cfg.UseClientUUID && loginStart.HasUUID {
+		return loginStart.UUID
+	}
+
+	return protocol.OfflinePlayerUUID(loginStart.Username)
+}
+
+func valueOrPlaceholder(value string, placeholder string) string {
+	if value == "" {
+		return placeholder
+	}
+
+	return value
+}
+
+func shouldProxyPlayer(username string, clientUUID string, cfg LoginConfig) bool {
 	if cfg.RealServerAddr == "" || cfg.IsWhitelisted == nil {
 		return false
 	}
 
-	return cfg.IsWhitelisted(username)
+	return cfg.IsWhitelisted(username, clientUUID)
 }
 
 func proxyToRealServer(clientConn net.Conn, serverAddr string, handshakePacket []byte, loginStartPacket []byte, username string) error {
@@ -218,10 +1085,7 @@ func proxyToRealServer(clientConn net.Conn, serverAddr string, handshakePacket [
 
 func relayTraffic(dst net.Conn, src net.Conn, errCh chan<- error) {
 	_, err := io.Copy(dst, src)
-
-	if tcpConn, ok := dst.(*net.TCPConn); ok {
-		_ = tcpConn.CloseWrite()
-	}
+	closeWrite(dst)
 
 	errCh <- err
 }
@@ -230,6 +1094,25 @@ func isRelayClosed(err error) bool {
 	return err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)
 }
 
+const (
+	acceptBackoffMin = 5 * time.Millisecond
+	acceptBackoffMax = time.Second
+)
+
+const (
+	disconnectLingerTimeout = 2 * time.Second
+	legacyPingReadTimeout   = 500 * time.Millisecond
+	sessionServerTimeout    = 10 * time.Second
+)
+
+const maxLoggedPayloadBytes = 64
+
+const (
+	serverKeyBits      = 1024
+	verifyTokenLength  = 4
+	sharedSecretLength = 16
+)
+
 const (
 	udpAuthorizationTTL = 10 * time.Minute
 	udpSessionTTL       = 10 * time.Minute
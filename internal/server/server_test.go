@@ -0,0 +1,277 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+const testProtocol = 763
+
+// startServer serves on a loopback port until the test ends.
+func startServer(t *testing.T, loginCfg LoginConfig, timeouts TimeoutConfig) *Server {
+	t.Helper()
+
+	server, err := Listen("127.0.0.1:0", StatusConfig{Protocol: testProtocol}, loginCfg, timeouts, Hooks{})
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve() }()
+	t.Cleanup(func() {
+		server.Shutdown()
+		if err := <-served; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+
+	return server
+}
+
+type testClient struct {
+	conn            net.Conn
+	reader          *protocol.PacketReader
+	protocolVersion int32
+}
+
+func dialServer(t *testing.T, server *Server, protocolVersion int32) *testClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	return &testClient{
+		conn:            conn,
+		reader:          protocol.NewPacketReader(conn, protocol.MaxPacketLength),
+		protocolVersion: protocolVersion,
+	}
+}
+
+func (c *testClient) send(t *testing.T, packet []byte) {
+	t.Helper()
+
+	if _, err := c.conn.Write(protocol.WrapPacket(packet)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+// read returns the id and payload of the next packet.
+func (c *testClient) read(t *testing.T) (int32, []byte) {
+	t.Helper()
+
+	packet, err := c.reader.ReadPacket()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	id, payload, err := protocol.ReadPacketID(packet)
+	if err != nil {
+		t.Fatalf("read packet id: %v", err)
+	}
+
+	return id, payload
+}
+
+// readUntil skips packets until one with the given id arrives.
+func (c *testClient) readUntil(t *testing.T, id int32) []byte {
+	t.Helper()
+
+	for {
+		if packetID, payload := c.read(t); packetID == id {
+			return payload
+		}
+	}
+}
+
+func (c *testClient) sendHandshake(t *testing.T, nextState int32) {
+	t.Helper()
+
+	handshake := protocol.EncodeVarInt(0x00)
+	handshake = append(handshake, protocol.EncodeVarInt(c.protocolVersion)...)
+	handshake = append(handshake, 0x09)
+	handshake = append(handshake, "localhost"...)
+	handshake = append(handshake, 0x63, 0xDD)
+	handshake = append(handshake, protocol.EncodeVarInt(nextState)...)
+	c.send(t, handshake)
+}
+
+// startLogin sends the handshake and Login Start of username.
+func (c *testClient) startLogin(t *testing.T, nextState int32, username string) {
+	t.Helper()
+
+	c.sendHandshake(t, nextState)
+	loginStart := append([]byte{0x00, byte(len(username))}, username...)
+	if c.protocolVersion >= 764 {
+		loginStart = append(loginStart, make([]byte, 16)...)
+	} else {
+		loginStart = append(loginStart, 0x00)
+	}
+	c.send(t, loginStart)
+}
+
+// enterPlay logs in as username and goes through the configuration state
+// when the version has one, up to Join Game.
+func (c *testClient) enterPlay(t *testing.T, username string) {
+	t.Helper()
+
+	c.startLogin(t, protocol.StateLogin, username)
+	c.readUntil(t, 0x02)
+
+	version := protocol.VersionFor(c.protocolVersion)
+	if version.HasConfigurationState {
+		c.send(t, []byte{byte(version.Login.LoginAcknowledged)})
+		c.readUntil(t, version.Configuration.FinishConfiguration)
+		c.send(t, []byte{byte(version.Configuration.FinishConfigurationAck)})
+	}
+	c.readUntil(t, version.Play.Login)
+}
+
+// prefixedBytes splits a VarInt-prefixed byte array off data.
+func prefixedBytes(t *testing.T, data []byte) ([]byte, []byte) {
+	t.Helper()
+
+	reader := bytes.NewReader(data)
+	length, err := protocol.ReadVarInt(reader)
+	if err != nil || int(length) > reader.Len() {
+		t.Fatalf("invalid byte array in %v", data)
+	}
+	start := len(data) - reader.Len()
+
+	return data[start : start+int(length)], data[start+int(length):]
+}
+
+func TestHandleLogin_WrongVerifyTokenDisconnectsEncrypted(t *testing.T) {
+	server := startServer(t, LoginConfig{OnlineMode: true, SessionServerURL: "http://127.0.0.1:1"}, TimeoutConfig{})
+	client := dialServer(t, server, testProtocol)
+	client.startLogin(t, protocol.StateLogin, "Steve")
+
+	id, payload := client.read(t)
+	if id != 0x01 {
+		t.Fatalf("expected Encryption Request, got packet 0x%02X", id)
+	}
+	_, payload = prefixedBytes(t, payload)
+	rawKey, _ := prefixedBytes(t, payload)
+	publicKey, err := x509.ParsePKIXPublicKey(rawKey)
+	if err != nil {
+		t.Fatalf("parse public key: %v", err)
+	}
+
+	sharedSecret := bytes.Repeat([]byte{0x42}, sharedSecretLength)
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey.(*rsa.PublicKey), sharedSecret)
+	if err != nil {
+		t.Fatalf("encrypt shared secret: %v", err)
+	}
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey.(*rsa.PublicKey), []byte("nope"))
+	if err != nil {
+		t.Fatalf("encrypt verify token: %v", err)
+	}
+
+	response := []byte{0x01}
+	response = append(response, protocol.EncodeVarInt(int32(len(encryptedSecret)))...)
+	response = append(response, encryptedSecret...)
+	response = append(response, protocol.EncodeVarInt(int32(len(encryptedToken)))...)
+	response = append(response, encryptedToken...)
+	client.send(t, response)
+
+	_, decrypt, err := protocol.NewEncryptionStreams(sharedSecret)
+	if err != nil {
+		t.Fatalf("NewEncryptionStreams failed: %v", err)
+	}
+	client.reader.Reset(cipher.StreamReader{S: decrypt, R: client.conn})
+
+	id, payload = client.read(t)
+	if id != 0x00 || !bytes.Contains(payload, []byte("multiplayer.disconnect.unverified_username")) {
+		t.Fatalf("expected an encrypted login disconnect, got packet 0x%02X %q", id, payload)
+	}
+}
+
+// flakyListener fails Accept with errs, one per call, then reports that it
+// was closed.
+type flakyListener struct {
+	errs []error
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if len(l.errs) == 0 {
+		return nil, &net.OpError{Op: "accept", Net: "tcp", Err: net.ErrClosed}
+	}
+	err := l.errs[0]
+	l.errs = l.errs[1:]
+	return nil, err
+}
+
+func (l *flakyListener) Close() error   { return nil }
+func (l *flakyListener) Addr() net.Addr { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+
+func acceptError(err error) error {
+	return &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept4", err)}
+}
+
+func TestServe_AcceptErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		errs         []error
+		wantErr      bool
+		wantFailures uint64
+		// minDuration is the backoff Serve has to wait at least.
+		minDuration time.Duration
+	}{
+		{name: "closed listener"},
+		{
+			name:         "out of file descriptors",
+			errs:         []error{acceptError(syscall.EMFILE), acceptError(syscall.ENFILE), acceptError(syscall.ECONNABORTED)},
+			wantFailures: 3,
+			minDuration:  acceptBackoffMin + 2*acceptBackoffMin + 4*acceptBackoffMin,
+		},
+		{
+			name:         "timeout",
+			errs:         []error{&net.OpError{Op: "accept", Net: "tcp", Err: os.ErrDeadlineExceeded}},
+			wantFailures: 1,
+			minDuration:  acceptBackoffMin,
+		},
+		{
+			name:         "permanent error",
+			errs:         []error{acceptError(syscall.EINVAL), acceptError(syscall.EMFILE)},
+			wantErr:      true,
+			wantFailures: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			server := &Server{
+				listener:    &flakyListener{errs: tt.errs},
+				connections: newConnectionSet(),
+				ctx:         ctx,
+				cancel:      cancel,
+			}
+
+			start := time.Now()
+			err := server.Serve()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minDuration {
+				t.Fatalf("expected Serve to back off for %s, returned after %s", tt.minDuration, elapsed)
+			}
+			if failures := server.AcceptFailures(); failures != tt.wantFailures {
+				t.Fatalf("expected %d accept failures, got %d", tt.wantFailures, failures)
+			}
+		})
+	}
+}
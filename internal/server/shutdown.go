@@ -0,0 +1,83 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultShutdownMessage is the vanilla reason shown when a server stops.
+const defaultShutdownMessage = `{"translate":"multiplayer.disconnect.server_shutdown"}`
+
+func shutdownMessage(cfg LoginConfig) string {
+	if cfg.ShutdownMessage == "" {
+		return defaultShutdownMessage
+	}
+
+	return cfg.ShutdownMessage
+}
+
+// connectionSet tracks the open client connections so that Run can wait for
+// them on shutdown and close the ones that outlive the drain timeout.
+type connectionSet struct {
+	mu    sync.Mutex
+	conns map[*timeoutConn]struct{}
+	wg    sync.WaitGroup
+}
+
+func newConnectionSet() *connectionSet {
+	return &connectionSet{conns: map[*timeoutConn]struct{}{}}
+}
+
+func (s *connectionSet) add(conn *timeoutConn) {
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+	s.wg.Add(1)
+}
+
+func (s *connectionSet) remove(conn *timeoutConn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	s.wg.Done()
+}
+
+func (s *connectionSet) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.conns)
+}
+
+// drain waits up to timeout for every connection to end, then closes the
+// remaining ones and gives their handlers a moment to return. It returns how
+// many had to be closed. Players held in play get at least
+// disconnectLingerTimeout to receive their shutdown message.
+func (s *connectionSet) drain(timeout time.Duration) int {
+	timeout = max(timeout, disconnectLingerTimeout)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-time.After(timeout):
+	}
+
+	s.mu.Lock()
+	closed := len(s.conns)
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(disconnectLingerTimeout):
+	}
+	return closed
+}
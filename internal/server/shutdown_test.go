@@ -0,0 +1,59 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+func TestShutdown_DrainsLimboPlayers(t *testing.T) {
+	tests := []struct {
+		name            string
+		protocolVersion int32
+		message         string
+		wantReason      string
+	}{
+		{name: "vanilla message", protocolVersion: 763, wantReason: "multiplayer.disconnect.server_shutdown"},
+		{name: "shutdown message", protocolVersion: 763, message: "Restarting", wantReason: "Restarting"},
+		{name: "NBT reason", protocolVersion: 769, message: "Restarting", wantReason: "Restarting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loginCfg := LoginConfig{Limbo: LimboConfig{Enabled: true}, ShutdownMessage: tt.message}
+			server, err := Listen("127.0.0.1:0", StatusConfig{}, loginCfg, TimeoutConfig{Drain: time.Minute}, Hooks{})
+			if err != nil {
+				t.Fatalf("Listen failed: %v", err)
+			}
+			served := make(chan error, 1)
+			go func() { served <- server.Serve() }()
+
+			players := []*testClient{dialServer(t, server, tt.protocolVersion), dialServer(t, server, tt.protocolVersion)}
+			for _, player := range players {
+				player.enterPlay(t, "Steve")
+			}
+			server.Shutdown()
+
+			disconnect := protocol.VersionFor(tt.protocolVersion).Play.Disconnect
+			for _, player := range players {
+				if reason := player.readUntil(t, disconnect); !bytes.Contains(reason, []byte(tt.wantReason)) {
+					t.Fatalf("expected a disconnect with %q, got %q", tt.wantReason, reason)
+				}
+				player.conn.Close()
+			}
+
+			// Serve returns as soon as the players are gone, well before the
+			// drain timeout.
+			select {
+			case err := <-served:
+				if err != nil {
+					t.Fatalf("Serve failed: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Serve did not return after the players left")
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// TimeoutConfig bounds how long a connection may stay in each phase. A zero
+// duration disables the corresponding deadline.
+type TimeoutConfig struct {
+	// Handshake is the time a new connection has to send its handshake.
+	Handshake time.Duration
+	// Status covers the status request and ping after the handshake.
+	Status time.Duration
+	// Login covers everything from Login Start until the player enters play or
+	// is proxied, ErrorDelay excluded.
+	Login time.Duration
+	// Idle is the longest a player in play or a proxied session may go
+	// without sending or accepting data.
+	Idle time.Duration
+	// Drain is how long Run waits for open connections once it is stopped.
+	Drain time.Duration
+}
+
+// TimeoutCounts is the number of connections of a Server that timed out in
+// each phase.
+type TimeoutCounts struct {
+	Handshake uint64
+	Status    uint64
+	Login     uint64
+	Idle      uint64
+}
+
+type connPhase int32
+
+const (
+	// phaseUntracked is used once the handlers set deadlines themselves,
+	// e.g. while lingering after a disconnect, so those are not reported.
+	phaseUntracked connPhase = iota
+	phaseHandshake
+	phaseStatus
+	phaseLogin
+	phaseIdle
+)
+
+// timeoutCounters counts the timed out connections of a Server by phase.
+type timeoutCounters [phaseIdle + 1]atomic.Uint64
+
+func (c *timeoutCounters) counts() TimeoutCounts {
+	return TimeoutCounts{
+		Handshake: c[phaseHandshake].Load(),
+		Status:    c[phaseStatus].Load(),
+		Login:     c[phaseLogin].Load(),
+		Idle:      c[phaseIdle].Load(),
+	}
+}
+
+// timeoutConn applies the deadline of the current phase to a client
+// connection and remembers whether it expired. In the idle phase the deadline
+// is pushed back on every read and write.
+type timeoutConn struct {
+	net.Conn
+	timeouts TimeoutConfig
+	counters *timeoutCounters
+
+	phase atomic.Int32
+	// timedOutIn is the phase whose deadline expired first, if any.
+	timedOutIn atomic.Int32
+}
+
+func newTimeoutConn(conn net.Conn, timeouts TimeoutConfig, counters *timeoutCounters) *timeoutConn {
+	c := &timeoutConn{Conn: conn, timeouts: timeouts, counters: counters}
+	c.enterPhase(phaseHandshake, timeouts.Handshake)
+	return c
+}
+
+func (c *timeoutConn) enterStatus() {
+	c.enterPhase(phaseStatus, c.timeouts.Status)
+}
+
+// enterLogin starts the login phase, extended by the time the login is held
+// on purpose.
+func (c *timeoutConn) enterLogin(delay time.Duration) {
+	timeout := c.timeouts.Login
+	if timeout > 0 {
+		timeout += delay
+	}
+	c.enterPhase(phaseLogin, timeout)
+}
+
+// enterIdle starts the idle phase, where the connection times out after
+// Idle without traffic.
+func (c *timeoutConn) enterIdle() {
+	c.enterPhase(phaseIdle, 0)
+}
+
+// enterPhase replaces the deadline of the previous phase with one timeout
+// from now, or none when timeout is zero.
+func (c *timeoutConn) enterPhase(phase connPhase, timeout time.Duration) {
+	c.phase.Store(int32(phase))
+
+	deadline := time.Time{}
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	_ = c.Conn.SetDeadline(deadline)
+}
+
+func (c *timeoutConn) Read(p []byte) (int, error) {
+	if c.timeouts.Idle > 0 && connPhase(c.phase.Load()) == phaseIdle {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeouts.Idle))
+	}
+
+	n, err := c.Conn.Read(p)
+	c.noteTimeout(err)
+	return n, err
+}
+
+func (c *timeoutConn) Write(p []byte) (int, error) {
+	if c.timeouts.Idle > 0 && connPhase(c.phase.Load()) == phaseIdle {
+		_ = c.Conn.SetWriteDeadline(time.Now().Add(c.timeouts.Idle))
+	}
+
+	n, err := c.Conn.Write(p)
+	c.noteTimeout(err)
+	return n, err
+}
+
+func (c *timeoutConn) SetDeadline(t time.Time) error {
+	c.phase.Store(int32(phaseUntracked))
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *timeoutConn) SetReadDeadline(t time.Time) error {
+	c.phase.Store(int32(phaseUntracked))
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *timeoutConn) SetWriteDeadline(t time.Time) error {
+	c.phase.Store(int32(phaseUntracked))
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// CloseWrite half-closes the underlying TCP connection.
+func (c *timeoutConn) CloseWrite() error {
+	if tcpConn, ok := c.Conn.(*net.TCPConn); ok {
+		return tcpConn.CloseWrite()
+	}
+
+	return nil
+}
+
+func (c *timeoutConn) noteTimeout(err error) {
+	if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+		return
+	}
+	c.timedOutIn.CompareAndSwap(int32(phaseUntracked), c.phase.Load())
+}
+
+// reportTimeout counts and logs the connection if one of its phase deadlines
+// expired.
+func (c *timeoutConn) reportTimeout() {
+	phase := connPhase(c.timedOutIn.Load())
+	if phase == phaseUntracked {
+		return
+	}
+	total := c.counters[phase].Add(1)
+
+	remote := c.RemoteAddr()
+	switch phase {
+	case phaseHandshake:
+		log.Printf("Handshake timed out for %s (%d handshake timeouts)", remote, total)
+	case phaseStatus:
+		log.Printf("Status request timed out for %s (%d status timeouts)", remote, total)
+	case phaseLogin:
+		log.Printf("Login timed out for %s (%d login timeouts)", remote, total)
+	case phaseIdle:
+		log.Printf("Connection from %s idle for %s, closed (%d idle timeouts)", remote, c.timeouts.Idle, total)
+	}
+}
+
+// closeWrite half-closes conn when it supports it, so that the client reads
+// everything sent so far before the connection goes away.
+func closeWrite(conn net.Conn) {
+	if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = closer.CloseWrite()
+	}
+}
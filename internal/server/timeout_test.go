@@ -0,0 +1,63 @@
+package server
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+func TestServe_CountsTimedOutConnections(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name string
+		// start brings the connection into the phase that should time out.
+		start    func(t *testing.T, client *testClient)
+		timeouts TimeoutConfig
+		want     TimeoutCounts
+	}{
+		{
+			name:     "silent handshake",
+			start:    func(*testing.T, *testClient) {},
+			timeouts: TimeoutConfig{Handshake: timeout},
+			want:     TimeoutCounts{Handshake: 1},
+		},
+		{
+			name:     "no status request",
+			start:    func(t *testing.T, client *testClient) { client.sendHandshake(t, protocol.StateStatus) },
+			timeouts: TimeoutConfig{Status: timeout},
+			want:     TimeoutCounts{Status: 1},
+		},
+		{
+			name:     "no login start",
+			start:    func(t *testing.T, client *testClient) { client.sendHandshake(t, protocol.StateLogin) },
+			timeouts: TimeoutConfig{Login: timeout},
+			want:     TimeoutCounts{Login: 1},
+		},
+		{
+			name:     "idle in play",
+			start:    func(t *testing.T, client *testClient) { client.enterPlay(t, "Steve") },
+			timeouts: TimeoutConfig{Idle: timeout},
+			want:     TimeoutCounts{Idle: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startServer(t, LoginConfig{PlayHoldTime: time.Minute}, tt.timeouts)
+
+			client := dialServer(t, server, testProtocol)
+			tt.start(t, client)
+
+			// The connection is closed once the timeout has been counted.
+			if _, err := io.Copy(io.Discard, client.conn); err != nil {
+				t.Fatalf("expected the server to close the connection, got %v", err)
+			}
+			if got := server.TimedOutConnections(); got != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the Mojang session server used by vanilla servers.
+const DefaultURL = "https://sessionserver.mojang.com"
+
+const (
+	hasJoinedPath     = "/session/minecraft/hasJoined"
+	httpClientTimeout = 10 * time.Second
+)
+
+// ErrNotVerified is returned by HasJoined when the session server does not
+// know about the join, which vanilla reports as "Failed to verify username!".
+var ErrNotVerified = errors.New("session server did not verify the player")
+
+type Profile struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Properties []Property `json:"properties"`
+}
+
+type Property struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the session server at baseURL, for example
+// DefaultURL or a local stand-in used in tests.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: httpClientTimeout},
+	}
+}
+
+// HasJoined asks the session server whether username has joined the server
+// identified by serverHash. ip is optional and only checked by the server
+// when it is not empty.
+func (c *Client) HasJoined(ctx context.Context, username string, serverHash string, ip string) (Profile, error) {
+	query := url.Values{}
+	query.Set("username", username)
+	query.Set("serverId", serverHash)
+	if ip != "" {
+		query.Set("ip", ip)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+hasJoinedPath+"?"+query.Encode(), nil)
+	if err != nil {
+		return Profile{}, fmt.Errorf("build hasJoined request: %w", err)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return Profile{}, fmt.Errorf("hasJoined request: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return Profile{}, ErrNotVerified
+	default:
+		return Profile{}, fmt.Errorf("hasJoined request: unexpected status %s", response.Status)
+	}
+
+	var profile Profile
+	if err := json.NewDecoder(response.Body).Decode(&profile); err != nil {
+		return Profile{}, fmt.Errorf("decode hasJoined profile: %w", err)
+	}
+	if profile.ID == "" || profile.Name == "" {
+		return Profile{}, fmt.Errorf("hasJoined profile is missing id or name")
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,38 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientHasJoined(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/session/minecraft/hasJoined" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("serverId") != "-1234" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"069a79f444e94726a5befca90e38aaf5","name":"Notch","properties":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/")
+
+	profile, err := client.HasJoined(context.Background(), "Notch", "-1234", "")
+	if err != nil {
+		t.Fatalf("HasJoined failed: %v", err)
+	}
+	if profile.Name != "Notch" || profile.ID != "069a79f444e94726a5befca90e38aaf5" {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+
+	if _, err := client.HasJoined(context.Background(), "Notch", "other", ""); !errors.Is(err, ErrNotVerified) {
+		t.Fatalf("expected ErrNotVerified, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+const (
+	joinPath        = "/session/minecraft/join"
+	maxJoinBodySize = 4096
+)
+
+// Account is a player known to the mock session server.
+type Account struct {
+	Name    string
+	UUID    protocol.UUID
+	SkinURL string
+}
+
+// Server is a stand-in for sessionserver.mojang.com implementing the two
+// endpoints used by the login handshake. Any access token is accepted, but the
+// selected profile must be one of the configured accounts.
+type Server struct {
+	accounts map[string]Account // keyed by lower-case name
+
+	mu    sync.Mutex
+	joins map[protocol.UUID]string // profile -> server hash of the last join
+}
+
+func NewServer(accounts []Account) *Server {
+	byName := make(map[string]Account, len(accounts))
+	for _, account := range accounts {
+		byName[strings.ToLower(account.Name)] = account
+	}
+
+	return &Server{
+		accounts: byName,
+		joins:    map[protocol.UUID]string{},
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case joinPath:
+		s.handleJoin(w, r)
+	case hasJoinedPath:
+		s.handleHasJoined(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type joinRequest struct {
+	AccessToken     string `json:"accessToken"`
+	SelectedProfile string `json:"selectedProfile"`
+	ServerID        string `json:"serverId"`
+}
+
+type errorResponse struct {
+	Error        string `json:"error"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request joinRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJoinBodySize)).Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "IllegalArgumentException", ErrorMessage: "Invalid join request"})
+		return
+	}
+
+	profileUUID, err := protocol.ParseUUID(request.SelectedProfile)
+	if err != nil || !s.hasAccount(profileUUID) {
+		log.Printf("Mock session server: rejected join for unknown profile %q", request.SelectedProfile)
+		writeJSON(w, http.StatusForbidden, errorResponse{Error: "ForbiddenOperationException", ErrorMessage: "Invalid token."})
+		return
+	}
+
+	s.mu.Lock()
+	s.joins[profileUUID] = request.ServerID
+	s.mu.Unlock()
+
+	log.Printf("Mock session server: profile %s joined server %s", profileUUID, request.ServerID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHasJoined(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := r.URL.Query().Get("username")
+	serverHash := r.URL.Query().Get("serverId")
+
+	account, ok := s.accounts[strings.ToLower(username)]
+	if !ok {
+		log.Printf("Mock session server: hasJoined for unknown username %q", username)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.mu.Lock()
+	joinedServer, joined := s.joins[account.UUID]
+	s.mu.Unlock()
+
+	if !joined || joinedServer != serverHash {
+		log.Printf("Mock session server: username %q has not joined server %s", username, serverHash)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, account.profile())
+}
+
+func (s *Server) hasAccount(profileUUID protocol.UUID) bool {
+	for _, account := range s.accounts {
+		if account.UUID == profileUUID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// profile returns the hasJoined response, including an unsigned textures
+// property; clients show unsigned skins with a warning in their log.
+func (a Account) profile() Profile {
+	id := hex.EncodeToString(a.UUID[:])
+
+	textures := map[string]any{}
+	if a.SkinURL != "" {
+		textures["SKIN"] = map[string]string{"url": a.SkinURL}
+	}
+
+	value, _ := json.Marshal(map[string]any{
+		"timestamp":   time.Now().UnixMilli(),
+		"profileId":   id,
+		"profileName": a.Name,
+		"textures":    textures,
+	})
+
+	return Profile{
+		ID:   id,
+		Name: a.Name,
+		Properties: []Property{
+			{Name: "textures", Value: base64.StdEncoding.EncodeToString(value)},
+		},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,84 @@
+package session
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+func TestServerJoinAndHasJoined(t *testing.T) {
+	notch := Account{
+		Name:    "Notch",
+		UUID:    protocol.OfflinePlayerUUID("Notch"),
+		SkinURL: "http://textures.example/notch.png",
+	}
+	server := httptest.NewServer(NewServer([]Account{notch}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, err := client.HasJoined(context.Background(), "Notch", "-1a2b", ""); !errors.Is(err, ErrNotVerified) {
+		t.Fatalf("expected ErrNotVerified before join, got %v", err)
+	}
+
+	join(t, server.URL, notch.UUID.String(), "-1a2b", http.StatusNoContent)
+
+	profile, err := client.HasJoined(context.Background(), "notch", "-1a2b", "")
+	if err != nil {
+		t.Fatalf("HasJoined failed: %v", err)
+	}
+	if profile.Name != "Notch" || profile.ID != strings.ReplaceAll(notch.UUID.String(), "-", "") {
+		t.Fatalf("unexpected profile: %+v", profile)
+	}
+	if len(profile.Properties) != 1 || profile.Properties[0].Name != "textures" {
+		t.Fatalf("expected a textures property, got %+v", profile.Properties)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(profile.Properties[0].Value)
+	if err != nil {
+		t.Fatalf("decode textures failed: %v", err)
+	}
+	var textures struct {
+		Textures map[string]struct {
+			URL string `json:"url"`
+		} `json:"textures"`
+	}
+	if err := json.Unmarshal(decoded, &textures); err != nil {
+		t.Fatalf("unmarshal textures failed: %v", err)
+	}
+	if textures.Textures["SKIN"].URL != notch.SkinURL {
+		t.Fatalf("unexpected skin: %+v", textures)
+	}
+
+	if _, err := client.HasJoined(context.Background(), "Notch", "other-server", ""); !errors.Is(err, ErrNotVerified) {
+		t.Fatalf("expected ErrNotVerified for another server hash, got %v", err)
+	}
+}
+
+func TestServerJoin_RejectsUnknownProfile(t *testing.T) {
+	server := httptest.NewServer(NewServer(nil))
+	defer server.Close()
+
+	join(t, server.URL, protocol.OfflinePlayerUUID("Steve").String(), "-1a2b", http.StatusForbidden)
+}
+
+func join(t *testing.T, baseURL string, profile string, serverID string, expectedStatus int) {
+	t.Helper()
+
+	body := `{"accessToken":"token","selectedProfile":"` + profile + `","serverId":"` + serverID + `"}`
+	response, err := http.Post(baseURL+"/session/minecraft/join", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("join request failed: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != expectedStatus {
+		t.Fatalf("expected join status %d, got %d", expectedStatus, response.StatusCode)
+	}
+}
@@ -1,20 +1,30 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"os"
+	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
-	"MineMock/internal/config"
-	"MineMock/internal/server"
+	"github.com/ketchandmayo/MineMock/internal/config"
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+	"github.com/ketchandmayo/MineMock/internal/server"
+	minemock "github.com/ketchandmayo/MineMock/server"
 )
 
 func main() {
+	configPath := flag.String("config", "", "YAML, TOML or JSON config file (default $MINEMOCK_CONFIG)")
+	lenient := flag.Bool("lenient", false, "start with default values in place of invalid settings instead of exiting")
+	flag.Parse()
+
 	logFile, err := os.OpenFile("server.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 	if err != nil {
 		fmt.Println("Failed to open server.log:", err)
@@ -25,34 +35,38 @@ func main() {
 	log.SetOutput(io.MultiWriter(os.Stdout, logFile))
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
-	cfg := config.FromEnv()
-	addr := cfg.Address()
-
-	statusCfg := server.StatusConfig{
-		MOTD:          cfg.MOTD,
-		VersionName:   cfg.VersionName,
-		Protocol:      cfg.Protocol,
-		MaxPlayers:    cfg.MaxPlayers,
-		OnlinePlayers: cfg.OnlinePlayers,
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Println("Config error:", err)
+		os.Exit(1)
+	}
+	if err := cfg.Validate(); err != nil {
+		if !*lenient {
+			log.Println(err)
+			log.Println("Fix the settings above, or start with --lenient to use the defaults for invalid values")
+			os.Exit(1)
+		}
+		log.Println("Starting anyway (--lenient):", err)
 	}
-	voicechatListenAddr := net.JoinHostPort(cfg.IP, strconv.Itoa(cfg.SimpleVoicechatPort))
+	opts := minemock.OptionsFromConfig(cfg)
+
 	writeBanner()
-	logServerConfig(cfg, voicechatListenAddr)
+	logServerConfig(cfg, opts.Login.SimpleVoicechatListenAddr)
 
-	loginCfg := server.LoginConfig{
-		ErrorMessage:               cfg.ErrorMessage,
-		ErrorDelay:                 cfg.ErrorDelay,
-		ForceConnectionLostTitle:   cfg.ForceConnectionLostTitle,
-		RealServerAddr:             cfg.RealServerAddr,
-		IsWhitelisted:              cfg.IsLoginWhitelisted,
-		SimpleVoicechatListenAddr:  voicechatListenAddr,
-		SimpleVoicechatBackendAddr: cfg.RealServerVoicechatAddress(),
+	// Validate already reported an unusable favicon; with --lenient the
+	// status response has no icon.
+	if favicon, err := cfg.Favicon(); err == nil {
+		opts.Status.Favicon = favicon
 	}
 
-	if err := server.Run(addr, statusCfg, loginCfg); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := server.Run(ctx, opts.Addr, opts.Status, opts.Login, opts.Timeouts); err != nil {
 		log.Println("Server error:", err)
 		os.Exit(1)
 	}
+	log.Println("Server stopped")
 }
 
 func logServerConfig(cfg config.Config, voicechatListenAddr string) {
@@ -72,6 +86,86 @@ func logServerConfig(cfg config.Config, voicechatListenAddr string) {
 		realServerAddr = "<empty>"
 	}
 
+	faviconPath := cfg.FaviconPath
+	if faviconPath == "" {
+		faviconPath = "<none>"
+	}
+
+	sampleNames := make([]string, 0, len(cfg.PlayerSample))
+	for _, player := range cfg.PlayerSample {
+		sampleNames = append(sampleNames, player.Name)
+	}
+	sampleText := "<empty>"
+	if len(sampleNames) > 0 {
+		sampleText = strings.Join(sampleNames, ", ")
+	}
+
+	mockSessionAddr := cfg.MockSessionServerAddr
+	if mockSessionAddr == "" {
+		mockSessionAddr = "<disabled>"
+	}
+
+	accountNames := make([]string, 0, len(cfg.MockSessionAccounts))
+	for _, account := range cfg.MockSessionAccounts {
+		accountNames = append(accountNames, account.Name)
+	}
+	accountsText := "<empty>"
+	if len(accountNames) > 0 {
+		accountsText = strings.Join(accountNames, ", ")
+	}
+
+	pluginChannels := make([]string, 0, len(cfg.LoginPluginRequests))
+	for _, request := range cfg.LoginPluginRequests {
+		pluginChannels = append(pluginChannels, request.Channel)
+	}
+	pluginText := "<empty>"
+	if len(pluginChannels) > 0 {
+		pluginText = strings.Join(pluginChannels, ", ")
+	}
+
+	transferRules := make([]string, 0, len(cfg.TransferRules))
+	for match, target := range cfg.TransferRules {
+		transferRules = append(transferRules, match+" -> "+target)
+	}
+	sort.Strings(transferRules)
+	transferText := "<empty>"
+	if len(transferRules) > 0 {
+		transferText = strings.Join(transferRules, ", ")
+	}
+
+	cookieRequestsText := "<empty>"
+	if len(cfg.CookieRequests) > 0 {
+		cookieRequestsText = strings.Join(cfg.CookieRequests, ", ")
+	}
+
+	storedCookies := make([]string, 0, len(cfg.StoreCookies))
+	for _, cookie := range cfg.StoreCookies {
+		storedCookies = append(storedCookies, fmt.Sprintf("%s=%q", cookie.Key, cookie.Payload))
+	}
+	storeCookiesText := "<empty>"
+	if len(storedCookies) > 0 {
+		storeCookiesText = strings.Join(storedCookies, ", ")
+	}
+
+	compressionText := "<disabled>"
+	if cfg.CompressionThreshold >= 0 {
+		compressionText = strconv.Itoa(int(cfg.CompressionThreshold))
+	}
+
+	queueDisplays := make([]string, 0, 2)
+	if cfg.QueueActionBar {
+		queueDisplays = append(queueDisplays, "actionbar")
+	}
+	if cfg.QueueBossBar {
+		queueDisplays = append(queueDisplays, "bossbar")
+	}
+	queueDisplayText := strings.Join(queueDisplays, ", ")
+
+	shutdownMessageText := "<vanilla>"
+	if cfg.ShutdownMessage != "" {
+		shutdownMessageText = protocol.ToLegacyText(cfg.ShutdownMessage)
+	}
+
 	voicechatBackendAddr := cfg.RealServerVoicechatAddress()
 	if strings.TrimSpace(voicechatBackendAddr) == "" {
 		voicechatBackendAddr = "<disabled>"
@@ -89,33 +183,122 @@ func logServerConfig(cfg config.Config, voicechatListenAddr string) {
 			"    protocol: %d\n"+
 			"    max_players: %d\n"+
 			"    online_players: %d\n"+
+			"    favicon: %s\n"+
+			"    player_sample: %s\n"+
+			"    enforces_secure_chat: %t\n"+
+			"    previews_chat: %t\n"+
 			"  [login]\n"+
+			"    error_message: %q\n"+
 			"    error_delay: %s\n"+
 			"    force_connection_lost_title: %t\n"+
 			"    real_server_addr: %s\n"+
 			"    whitelist_size: %d\n"+
 			"    whitelist: %s\n"+
+			"    use_client_uuid: %t\n"+
+			"    compression_threshold: %s\n"+
+			"    online_mode: %t\n"+
+			"    session_server_url: %s\n"+
+			"    login_plugin_requests: %s\n"+
+			"    login_plugin_require_understood: %t\n"+
+			"    transfer_rules: %s\n"+
+			"    cookie_requests: %s\n"+
+			"    store_cookies: %s\n"+
+			"  [limbo]\n"+
+			"    enabled: %t\n"+
+			"    title: %q\n"+
+			"    subtitle: %q\n"+
+			"    chat: %q\n"+
+			"    actionbar: %q\n"+
+			"  [queue]\n"+
+			"    enabled: %t\n"+
+			"    throughput_per_minute: %d\n"+
+			"    release: %s\n"+
+			"    display: %s\n"+
+			"    message: %q\n"+
+			"    release_message: %q\n"+
+			"  [play]\n"+
+			"    keep_alive_interval: %s\n"+
+			"    keep_alive_timeout: %s\n"+
+			"    hold: %s\n"+
+			"  [timeouts]\n"+
+			"    handshake: %s\n"+
+			"    status: %s\n"+
+			"    login: %s\n"+
+			"    idle: %s\n"+
+			"  [shutdown]\n"+
+			"    message: %q\n"+
+			"    drain_timeout: %s\n"+
+			"  [mock_session_server]\n"+
+			"    listen_addr: %s\n"+
+			"    accounts: %s\n"+
+			"  [text]\n"+
+			"    ampersand_color_codes: %t\n"+
 			"  [voicechat]\n"+
 			"    listen_addr: %s\n"+
 			"    backend_addr: %s",
 		cfg.Address(),
 		cfg.IP,
 		cfg.Port,
-		cfg.MOTD,
+		protocol.ToLegacyText(cfg.MOTD),
 		cfg.VersionName,
 		cfg.Protocol,
 		cfg.MaxPlayers,
 		cfg.OnlinePlayers,
+		faviconPath,
+		sampleText,
+		cfg.EnforcesSecureChat,
+		cfg.PreviewsChat,
+		protocol.ToLegacyText(cfg.ErrorMessage),
 		cfg.ErrorDelay,
 		cfg.ForceConnectionLostTitle,
 		realServerAddr,
 		len(cfg.LoginWhitelist),
 		whitelistText,
+		cfg.UseClientUUID,
+		compressionText,
+		cfg.OnlineMode,
+		cfg.SessionServerURL,
+		pluginText,
+		cfg.LoginPluginRequireUnderstood,
+		transferText,
+		cookieRequestsText,
+		storeCookiesText,
+		cfg.Limbo,
+		protocol.ToLegacyText(cfg.LimboTitle),
+		protocol.ToLegacyText(cfg.LimboSubtitle),
+		protocol.ToLegacyText(cfg.LimboChat),
+		protocol.ToLegacyText(cfg.LimboActionBar),
+		cfg.Queue,
+		cfg.QueueThroughput,
+		cfg.QueueRelease,
+		queueDisplayText,
+		protocol.ToLegacyText(cfg.QueueMessage),
+		protocol.ToLegacyText(cfg.QueueReleaseMessage),
+		cfg.KeepAliveInterval,
+		cfg.KeepAliveTimeout,
+		cfg.PlayHoldTime,
+		timeoutText(cfg.HandshakeTimeout),
+		timeoutText(cfg.StatusTimeout),
+		timeoutText(cfg.LoginTimeout),
+		timeoutText(cfg.IdleTimeout),
+		shutdownMessageText,
+		cfg.DrainTimeout,
+		mockSessionAddr,
+		accountsText,
+		cfg.AmpersandColorCodes,
 		voicechatListenAddr,
 		voicechatBackendAddr,
 	)
 }
 
+func timeoutText(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "<disabled>"
+	}
+
+	return timeout.String()
+}
+
 func writeBanner() {
 	banner := `
 	 ░  ░░░░  ░░        ░░   ░░░  ░░        ░░  ░░░░  ░░░      ░░░░      ░░░  ░░░░  ░
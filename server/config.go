@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/ketchandmayo/MineMock/internal/config"
+	internal "github.com/ketchandmayo/MineMock/internal/server"
+)
+
+// OptionsFromConfig maps the settings of the MineMock binary to Options, with
+// the voice chat proxy listening on IP next to the TCP server. The favicon is
+// left to the caller, which reads FaviconPath.
+func OptionsFromConfig(c config.Config) Options {
+	return Options{
+		Addr:     c.Address(),
+		Status:   statusConfig(c),
+		Login:    loginConfig(c),
+		Timeouts: timeoutConfig(c),
+	}
+}
+
+func statusConfig(c config.Config) StatusConfig {
+	return StatusConfig{
+		MOTD:               c.MOTD,
+		VersionName:        c.VersionName,
+		Protocol:           c.Protocol,
+		MaxPlayers:         c.MaxPlayers,
+		OnlinePlayers:      c.OnlinePlayers,
+		PlayerSample:       c.PlayerSample,
+		EnforcesSecureChat: c.EnforcesSecureChat,
+		PreviewsChat:       c.PreviewsChat,
+	}
+}
+
+func loginConfig(c config.Config) LoginConfig {
+	return LoginConfig{
+		ErrorMessage:                 c.ErrorMessage,
+		ErrorDelay:                   c.ErrorDelay,
+		ForceConnectionLostTitle:     c.ForceConnectionLostTitle,
+		RealServerAddr:               c.RealServerAddr,
+		IsWhitelisted:                c.IsLoginWhitelisted,
+		UseClientUUID:                c.UseClientUUID,
+		EnableCompression:            c.CompressionThreshold >= 0,
+		CompressionThreshold:         c.CompressionThreshold,
+		OnlineMode:                   c.OnlineMode,
+		SessionServerURL:             c.SessionServerURL,
+		MockSessionServerAddr:        c.MockSessionServerAddr,
+		MockSessionAccounts:          c.MockSessionAccounts,
+		LoginPluginRequests:          c.LoginPluginRequests,
+		RequireLoginPluginUnderstood: c.LoginPluginRequireUnderstood,
+		TransferTarget:               c.TransferTarget,
+		CookieRequests:               c.CookieRequests,
+		StoreCookies:                 c.StoreCookies,
+		Limbo: LimboConfig{
+			Enabled:   c.Limbo,
+			Title:     c.LimboTitle,
+			Subtitle:  c.LimboSubtitle,
+			Chat:      c.LimboChat,
+			ActionBar: c.LimboActionBar,
+		},
+		Queue: QueueConfig{
+			Enabled:        c.Queue,
+			Throughput:     c.QueueThroughput,
+			Release:        internal.QueueRelease(c.QueueRelease),
+			Message:        c.QueueMessage,
+			ActionBar:      c.QueueActionBar,
+			BossBar:        c.QueueBossBar,
+			ReleaseMessage: c.QueueReleaseMessage,
+		},
+		KeepAliveInterval:          c.KeepAliveInterval,
+		KeepAliveTimeout:           c.KeepAliveTimeout,
+		PlayHoldTime:               c.PlayHoldTime,
+		ShutdownMessage:            c.ShutdownMessage,
+		SimpleVoicechatListenAddr:  net.JoinHostPort(c.IP, strconv.Itoa(c.SimpleVoicechatPort)),
+		SimpleVoicechatBackendAddr: c.RealServerVoicechatAddress(),
+	}
+}
+
+func timeoutConfig(c config.Config) TimeoutConfig {
+	return TimeoutConfig{
+		Handshake: c.HandshakeTimeout,
+		Status:    c.StatusTimeout,
+		Login:     c.LoginTimeout,
+		Idle:      c.IdleTimeout,
+		Drain:     c.DrainTimeout,
+	}
+}
@@ -0,0 +1,172 @@
+// Package server runs MineMock in-process, for example to give every
+// integration test of a bot its own mock server:
+//
+//	srv := server.New(server.DefaultOptions())
+//	addr, err := srv.Start()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//
+// The server logs through the standard log package like the binary does.
+package server
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ketchandmayo/MineMock/internal/config"
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+	internal "github.com/ketchandmayo/MineMock/internal/server"
+	"github.com/ketchandmayo/MineMock/internal/session"
+)
+
+// The configuration and event types are those of the MineMock binary.
+type (
+	StatusConfig  = internal.StatusConfig
+	LoginConfig   = internal.LoginConfig
+	TimeoutConfig = internal.TimeoutConfig
+	TimeoutCounts = internal.TimeoutCounts
+	LimboConfig   = internal.LimboConfig
+	QueueConfig   = internal.QueueConfig
+	QueueRelease  = internal.QueueRelease
+
+	Hooks                 = internal.Hooks
+	HandshakeEvent        = internal.HandshakeEvent
+	LoginEvent            = internal.LoginEvent
+	ConnectionClosedEvent = internal.ConnectionClosedEvent
+
+	Handshake          = protocol.Handshake
+	UUID               = protocol.UUID
+	StatusPlayerSample = protocol.StatusPlayerSample
+	LoginPluginRequest = protocol.LoginPluginRequest
+	Cookie             = protocol.Cookie
+	Account            = session.Account
+)
+
+const (
+	QueueReleaseTransfer = internal.QueueReleaseTransfer
+	QueueReleaseProxy    = internal.QueueReleaseProxy
+)
+
+// DefaultAddr listens on a free loopback port.
+const DefaultAddr = "127.0.0.1:0"
+
+type Options struct {
+	// Addr is the TCP address to listen on, DefaultAddr when empty.
+	Addr     string
+	Status   StatusConfig
+	Login    LoginConfig
+	Timeouts TimeoutConfig
+	Hooks    Hooks
+}
+
+// DefaultOptions returns the defaults of the MineMock binary, on DefaultAddr,
+// without the voice chat proxy and without drain timeout so that Close
+// returns quickly.
+func DefaultOptions() Options {
+	opts := OptionsFromConfig(config.Defaults())
+	opts.Addr = DefaultAddr
+	opts.Login.SimpleVoicechatListenAddr = ""
+	opts.Timeouts.Drain = 0
+
+	return opts
+}
+
+// Server is a MineMock server started with Start and stopped with Close.
+type Server struct {
+	opts Options
+
+	mu       sync.Mutex
+	server   *internal.Server
+	served   chan struct{}
+	serveErr error
+}
+
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// Start listens on Options.Addr and serves in the background. It returns the
+// bound address, with the port picked by the system when Addr has port 0.
+func (s *Server) Start() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return "", errors.New("server already started")
+	}
+
+	addr := s.opts.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	server, err := internal.Listen(addr, s.opts.Status, s.opts.Login, s.opts.Timeouts, s.opts.Hooks)
+	if err != nil {
+		return "", err
+	}
+	s.server = server
+	s.served = make(chan struct{})
+	go func() {
+		s.serveErr = server.Serve()
+		close(s.served)
+	}()
+
+	return server.Addr().String(), nil
+}
+
+// Addr returns the address the server listens on, or "" before Start.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		return ""
+	}
+
+	return s.server.Addr().String()
+}
+
+// AcceptFailures returns the number of failed Accept calls on the listener,
+// or 0 before Start.
+func (s *Server) AcceptFailures() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		return 0
+	}
+
+	return s.server.AcceptFailures()
+}
+
+// TimedOutConnections returns how many connections timed out in each phase,
+// or zero counts before Start.
+func (s *Server) TimedOutConnections() TimeoutCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		return TimeoutCounts{}
+	}
+
+	return s.server.TimedOutConnections()
+}
+
+// Close stops the server like a SIGTERM stops the binary and waits for it:
+// players held in play are disconnected and the other connections are closed
+// after Timeouts.Drain.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	server, served := s.server, s.served
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	server.Shutdown()
+	<-served
+	return s.serveErr
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ketchandmayo/MineMock/internal/config"
+	"github.com/ketchandmayo/MineMock/internal/protocol"
+)
+
+func dial(t *testing.T, addr string, nextState int32, packets ...[]byte) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := protocol.EncodeVarInt(0x00)
+	handshake = append(handshake, protocol.EncodeVarInt(763)...)
+	handshake = append(handshake, 0x09)
+	handshake = append(handshake, "localhost"...)
+	handshake = append(handshake, 0x63, 0xDD)
+	handshake = append(handshake, protocol.EncodeVarInt(nextState)...)
+	for _, packet := range append([][]byte{handshake}, packets...) {
+		if _, err := conn.Write(protocol.WrapPacket(packet)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	return conn, bufio.NewReader(conn)
+}
+
+func TestServer_StartsOnEphemeralPorts(t *testing.T) {
+	handshakes := make(chan HandshakeEvent, 2)
+	opts := DefaultOptions()
+	opts.Hooks.OnHandshake = func(event HandshakeEvent) { handshakes <- event }
+
+	first, second := New(opts), New(opts)
+	firstAddr, err := first.Start()
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer first.Close()
+	secondAddr, err := second.Start()
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer second.Close()
+
+	if firstAddr == secondAddr || first.Addr() != firstAddr {
+		t.Fatalf("expected two distinct addresses, got %s and %s", firstAddr, secondAddr)
+	}
+
+	conn, reader := dial(t, secondAddr, protocol.StateStatus, []byte{0x00})
+	packet, err := protocol.ReadPacket(reader)
+	if err != nil {
+		t.Fatalf("read status response: %v", err)
+	}
+	conn.Close()
+	if id, _, _ := protocol.ReadPacketID(packet); id != 0x00 {
+		t.Fatalf("expected status response, got packet 0x%02X", id)
+	}
+
+	event := <-handshakes
+	if event.Handshake.NextState != protocol.StateStatus || event.Handshake.Host != "localhost" {
+		t.Fatalf("unexpected handshake event: %+v", event)
+	}
+}
+
+func TestServer_LoginHookAndClose(t *testing.T) {
+	logins := make(chan LoginEvent, 1)
+	closed := make(chan ConnectionClosedEvent, 1)
+	opts := DefaultOptions()
+	opts.Login.PlayHoldTime = time.Minute
+	opts.Login.ShutdownMessage = "bye"
+	opts.Hooks.OnLogin = func(event LoginEvent) { logins <- event }
+	opts.Hooks.OnConnectionClosed = func(event ConnectionClosedEvent) { closed <- event }
+
+	srv := New(opts)
+	addr, err := srv.Start()
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := srv.Start(); err == nil {
+		t.Fatal("expected a second Start to fail")
+	}
+
+	loginStart := append([]byte{0x00, 0x05}, "Steve"...)
+	loginStart = append(loginStart, 0x00)
+	conn, reader := dial(t, addr, protocol.StateLogin, loginStart)
+
+	event := <-logins
+	if event.Username != "Steve" || event.UUID != protocol.OfflinePlayerUUID("Steve") || event.Proxied {
+		t.Fatalf("unexpected login event: %+v", event)
+	}
+
+	// Wait until the player is in play, then stop the server.
+	for {
+		packet, err := protocol.ReadPacket(reader)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if id, _, _ := protocol.ReadPacketID(packet); id == 0x3C {
+			break
+		}
+	}
+	go srv.Close()
+
+	for {
+		packet, err := protocol.ReadPacket(reader)
+		if err != nil {
+			t.Fatalf("expected a shutdown disconnect, got %v", err)
+		}
+		if id, payload, _ := protocol.ReadPacketID(packet); id == 0x1A {
+			if string(payload[1:]) != `{"text":"bye"}` {
+				t.Fatalf("unexpected disconnect reason %q", payload)
+			}
+			conn.Close()
+			break
+		}
+	}
+
+	<-closed
+	if err := srv.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestOptionsFromConfig(t *testing.T) {
+	t.Setenv("IP", "0.0.0.0")
+	t.Setenv("COMPRESSION_THRESHOLD", "256")
+	t.Setenv("DRAIN_TIMEOUT_SECONDS", "3")
+
+	opts := OptionsFromConfig(config.FromEnv())
+	if opts.Addr != "0.0.0.0:25565" || opts.Login.SimpleVoicechatListenAddr != "0.0.0.0:24454" {
+		t.Fatalf("unexpected addresses: %q and %q", opts.Addr, opts.Login.SimpleVoicechatListenAddr)
+	}
+	if !opts.Login.EnableCompression || opts.Login.CompressionThreshold != 256 || opts.Timeouts.Drain != 3*time.Second {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+
+	defaults := DefaultOptions()
+	if defaults.Login.EnableCompression || defaults.Login.KeepAliveInterval != 10*time.Second || defaults.Timeouts.Drain != 0 {
+		t.Fatalf("unexpected default options: %+v", defaults)
+	}
+}